@@ -1,15 +1,22 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/gosimple/slug"
 	"github.com/nathabonfim59/md-fetch/internal/browser"
+	"github.com/nathabonfim59/md-fetch/internal/chunks"
+	"github.com/nathabonfim59/md-fetch/internal/config"
+	"github.com/nathabonfim59/md-fetch/internal/converter"
 	"github.com/nathabonfim59/md-fetch/internal/fetcher"
 	"github.com/nathabonfim59/md-fetch/internal/server"
+	"github.com/nathabonfim59/md-fetch/internal/tokens"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -17,6 +24,8 @@ var (
 	save        bool
 	filename    string
 	port        int
+	configPath  string
+	opts        = fetcher.DefaultOptions()
 )
 
 var rootCmd = &cobra.Command{
@@ -26,18 +35,48 @@ var rootCmd = &cobra.Command{
 Supports multiple browsers and can bypass anti-scraping measures.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := loadConfig(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		url := args[0]
-		content, err := fetcher.FetchContent(url, browserType)
+		if save && filename == "" {
+			filename = slug.Make(url) + opts.Format.Extension()
+			if opts.Chunk {
+				filename = slug.Make(url) + ".jsonl"
+			}
+		}
+		if opts.Images == converter.ImageDownload {
+			// Images are linked relative to the saved file, or to the
+			// working directory when printing
+			outputDir := "."
+			if save {
+				outputDir = filepath.Dir(filename)
+			}
+			opts.EnableImageDownloads(outputDir)
+		}
+
+		result, err := fetcher.Fetch(url, browserType, opts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-
-		if save {
-			if filename == "" {
-				filename = slug.Make(url) + ".md"
+		content := result.Content
+		if opts.Chunk {
+			// Chunks are written as JSON Lines, one chunk per line
+			var b strings.Builder
+			encoder := json.NewEncoder(&b)
+			for _, chunk := range result.Chunks {
+				if err := encoder.Encode(chunk); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
 			}
+			content = strings.TrimSuffix(b.String(), "\n")
+		}
 
+		if save {
 			fmt.Printf("Saving content to %s\n", filename)
 			file, err := os.Create(filename)
 			if err != nil {
@@ -62,7 +101,13 @@ var serveCmd = &cobra.Command{
 	Long: `Start md-fetch in HTTP server mode. This provides a REST API for fetching content
 from multiple URLs in parallel.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := loadConfig(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		srv := server.New(port)
+		srv.SetDefaults(browserType, opts)
 		if err := srv.Start(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error starting server: %v\n", err)
 			os.Exit(1)
@@ -70,15 +115,114 @@ from multiple URLs in parallel.`,
 	},
 }
 
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose available browser backends",
+	Long: `Report every browser backend md-fetch can find, with its path and version,
+and check headless mode, sandbox and permission issues by rendering a built-in
+test page through each backend.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		healthy := false
+		for _, d := range browser.Diagnose() {
+			if d.Path == "" {
+				fmt.Printf("%s: not found (searched PATH for %s)\n\n", d.Browser, strings.Join(d.Searched, ", "))
+				continue
+			}
+
+			header := fmt.Sprintf("%s: %s", d.Browser, d.Path)
+			if d.Version != "" {
+				header += " (" + d.Version + ")"
+			}
+			if d.Default {
+				header += " [default]"
+			}
+			fmt.Println(header)
+			for _, c := range d.Checks {
+				fmt.Printf("  %-4s %-12s %s\n", c.Status, c.Name, c.Detail)
+			}
+			fmt.Println()
+
+			if d.Healthy() {
+				healthy = true
+			}
+		}
+
+		if !healthy {
+			fmt.Fprintln(os.Stderr, "Error: no working browser backend found")
+			os.Exit(1)
+		}
+	},
+}
+
+// loadConfig reads the configuration file into the flag-bound settings.
+// Flags given on the command line take precedence over the file.
+func loadConfig(cmd *cobra.Command) error {
+	changed := make(map[string]string)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		changed[f.Name] = f.Value.String()
+	})
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	browserType = cfg.Browser
+	*opts = cfg.Options
+
+	for name, value := range changed {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return err
+		}
+	}
+	return opts.LoadRules()
+}
+
 func init() {
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", fmt.Sprintf("Configuration file (optional, defaults to %s)", config.DefaultPath()))
+	rootCmd.PersistentFlags().StringVar(&opts.RulesDir, "rules-dir", "", fmt.Sprintf("Directory of per-site extraction rules (optional, defaults to %s)", config.DefaultRulesDir()))
+
 	// Root command flags
 	rootCmd.Flags().StringVarP(&browserType, "browser", "b", "", fmt.Sprintf("Browser to use (optional, defaults to %s)", strings.Join(browser.DefaultBrowsers, " > ")))
 	rootCmd.Flags().BoolVarP(&save, "save", "s", false, "Save content to a file with slugified URL name")
 	rootCmd.Flags().StringVarP(&filename, "filename", "f", "", "Custom filename to save the content (optional, defaults to slugified URL)")
 
+	// Cleaning flags
+	rootCmd.Flags().BoolVar(&opts.KeepHeader, "keep-header", false, "Keep <header> elements")
+	rootCmd.Flags().BoolVar(&opts.KeepFooter, "keep-footer", false, "Keep <footer> elements")
+	rootCmd.Flags().BoolVar(&opts.KeepNav, "keep-nav", false, "Keep <nav> elements")
+	rootCmd.Flags().BoolVar(&opts.KeepStyles, "keep-styles", false, "Keep inline and internal styles")
+	rootCmd.Flags().BoolVar(&opts.KeepComments, "keep-comments", false, "Keep HTML comments")
+	rootCmd.Flags().BoolVar(&opts.KeepHidden, "keep-hidden", false, "Keep hidden and visually hidden elements")
+	rootCmd.Flags().BoolVar(&opts.KeepRelativeURLs, "keep-relative-urls", false, "Keep relative link and image URLs instead of making them absolute")
+	rootCmd.Flags().BoolVar(&opts.KeepTrackingParams, "keep-tracking-params", false, "Keep tracking parameters (utm_*, fbclid, ...) and redirect wrappers in links")
+	rootCmd.Flags().BoolVar(&opts.ComputedVisibility, "computed-visibility", false, "Detect elements hidden by stylesheets using the rendered page (Chrome only)")
+	rootCmd.Flags().BoolVar(&opts.InlineFrames, "inline-frames", false, "Inline the content of same-origin iframes (Chrome only)")
+	rootCmd.Flags().StringVar(&opts.FrameDomains, "frame-domains", "", "Comma-separated domains whose iframes are inlined too (e.g. \"docs.example.com,codepen.io\")")
+	rootCmd.Flags().BoolVar(&opts.ShadowDOM, "shadow-dom", false, "Include the content of open shadow roots (Chrome only)")
+	rootCmd.Flags().StringVar(&opts.Select, "select", "", "Keep only elements matching this CSS selector (e.g. \"article.main\")")
+	rootCmd.Flags().StringVar(&opts.Remove, "remove", "", "Remove elements matching this CSS selector before conversion (e.g. \".ads, .share-bar\")")
+	rootCmd.Flags().BoolVarP(&opts.Readability, "readability", "r", false, "Extract only the main article content, dropping sidebars, related links and comments")
+	rootCmd.Flags().StringVar((*string)(&opts.Format), "format", string(converter.FormatMarkdown), "Output format: markdown, text (plain prose), html (cleaned HTML), json (structured blocks with metadata) or asciidoc")
+	rootCmd.Flags().IntVar(&opts.MaxTokens, "max-tokens", 0, "Truncate the output to at most this many tokens at a section boundary (optional, 0 for no limit)")
+	rootCmd.Flags().StringVar((*string)(&opts.Tokenizer), "tokenizer", string(tokens.CL100K), "Tokenizer to count tokens for: cl100k_base or o200k_base")
+	rootCmd.Flags().BoolVar(&opts.Chunk, "chunk", false, "Split the Markdown along its headings into chunks, written as JSON Lines with their heading path, URL, index and offsets")
+	rootCmd.Flags().IntVar(&opts.ChunkSize, "chunk-size", chunks.DefaultSize, "Maximum tokens of a chunk")
+	rootCmd.Flags().IntVar(&opts.ChunkOverlap, "chunk-overlap", chunks.DefaultOverlap, "Tokens a chunk repeats from the end of the previous chunk in its section")
+	rootCmd.Flags().StringVar((*string)(&opts.Links), "links", string(converter.LinkInline), "How to render links: inline, reference (numbered references at the end), text (drop links) or appendix (text with a numbered link list)")
+	rootCmd.Flags().BoolVar(&opts.FrontMatter, "front-matter", false, "Prepend the page metadata (title, author, dates, canonical URL, ...) as YAML front matter")
+	rootCmd.Flags().BoolVar(&opts.StructuredData, "structured-data", false, "Add the JSON-LD, microdata and RDFa items of the page (recipes, products, events, articles, ...) to the front matter, or to the JSON document")
+	rootCmd.Flags().BoolVar(&opts.FeedEntries, "feed-entries", false, "For RSS and Atom feeds, fetch the full article of the first 20 entries into the digest instead of its summary")
+	rootCmd.Flags().StringVar((*string)(&opts.Images), "images", string(converter.ImageKeep), "How to render images: keep, alt (alt text only), drop or download (save into the assets directory and link the local copies)")
+	rootCmd.Flags().StringVar((*string)(&opts.ComplexTables), "complex-tables", string(converter.TableHTML), "How to render tables with merged cells, nested tables or block content: html (cleaned HTML) or flatten (one list item per row)")
+	rootCmd.Flags().BoolVar(&opts.NormalizeHeadings, "normalize-headings", false, "Rewrite heading levels into a clean outline: a single H1, no skipped levels and no empty headings")
+	rootCmd.Flags().BoolVar(&opts.TOC, "toc", false, "Add a table of contents linking to the headings of the Markdown")
+	rootCmd.Flags().StringVar(&opts.AssetsDir, "assets-dir", "", fmt.Sprintf("Directory downloaded images are saved in, relative to the saved Markdown file (optional, defaults to %s)", fetcher.DefaultAssetsDir))
+
 	// Server command flags
 	serveCmd.Flags().IntVarP(&port, "port", "p", 8080, "Port for HTTP server")
 	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(doctorCmd)
 }
 
 func main() {
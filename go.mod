@@ -4,14 +4,18 @@ go 1.23.0
 
 require (
 	github.com/JohannesKaufmann/html-to-markdown/v2 v2.2.2
+	github.com/andybalholm/cascadia v1.3.3
 	github.com/gosimple/slug v1.15.0
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
 	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
 	golang.org/x/net v0.38.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/JohannesKaufmann/dom v0.2.0 // indirect
 	github.com/gosimple/unidecode v1.0.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
@@ -0,0 +1,171 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxImageSize bounds the size of a downloaded image
+const maxImageSize = 50 << 20
+
+// errImageTooLarge is returned for images larger than maxImageSize, which are
+// not saved at all
+var errImageTooLarge = fmt.Errorf("image larger than %d MB", maxImageSize>>20)
+
+// Extensions kept from the image URL; other files are named after their
+// Content-Type
+var imageExtensions = map[string]bool{
+	".apng": true,
+	".avif": true,
+	".bmp":  true,
+	".gif":  true,
+	".ico":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+	".svg":  true,
+	".webp": true,
+}
+
+// Downloader saves images into a local directory. Files are named after a
+// hash of their URL, so fetching a page again reuses the same names and skips
+// images that are already on disk. Download may be called concurrently; the
+// same image downloaded twice at once is renamed into place twice, which
+// leaves one complete file.
+type Downloader struct {
+	dir    string
+	prefix string
+	client *http.Client
+
+	mu    sync.Mutex
+	saved map[string]string
+}
+
+// NewDownloader returns a Downloader saving files into dir and linking them
+// as prefix/name, where prefix is dir relative to the Markdown file
+func NewDownloader(dir, prefix string) *Downloader {
+	return &Downloader{
+		dir:    dir,
+		prefix: prefix,
+		client: &http.Client{Timeout: 30 * time.Second},
+		saved:  make(map[string]string),
+	}
+}
+
+// Download saves the image at src and returns its local path
+func (d *Downloader) Download(src string) (string, error) {
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("cannot download image %q: not an http or https URL", src)
+	}
+
+	d.mu.Lock()
+	local, ok := d.saved[src]
+	d.mu.Unlock()
+	if ok {
+		return local, nil
+	}
+
+	base := fileBase(src)
+	if ext := strings.ToLower(path.Ext(u.Path)); imageExtensions[ext] {
+		if _, err := os.Stat(filepath.Join(d.dir, base+ext)); err == nil {
+			return d.remember(src, base+ext), nil
+		}
+	}
+
+	resp, err := d.client.Get(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download image %s: HTTP %d", src, resp.StatusCode)
+	}
+	if resp.ContentLength > maxImageSize {
+		return "", fmt.Errorf("failed to download image %s: %w", src, errImageTooLarge)
+	}
+
+	name := base + extension(u, resp.Header.Get("Content-Type"))
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create assets directory: %v", err)
+	}
+	if err := writeFile(filepath.Join(d.dir, name), resp.Body, maxImageSize); err != nil {
+		return "", fmt.Errorf("failed to save image %s: %w", src, err)
+	}
+	return d.remember(src, name), nil
+}
+
+func (d *Downloader) remember(src, name string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	local := path.Join(d.prefix, name)
+	d.saved[src] = local
+	return local
+}
+
+// fileBase returns the deterministic file name of an image URL, without
+// extension
+func fileBase(src string) string {
+	sum := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(sum[:8])
+}
+
+// extension picks the file extension from the URL path, falling back to the
+// Content-Type of the response
+func extension(u *url.URL, contentType string) string {
+	if ext := strings.ToLower(path.Ext(u.Path)); imageExtensions[ext] {
+		return ext
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch mediaType {
+		case "image/jpeg":
+			return ".jpg"
+		case "image/svg+xml":
+			return ".svg"
+		}
+		if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	return ".img"
+}
+
+// writeFile writes through a temporary file so an interrupted download never
+// leaves a partial image under the final name. Content longer than limit is
+// an error rather than cut short.
+func writeFile(name string, r io.Reader, limit int64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".download-*")
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(tmp, io.LimitReader(r, limit+1))
+	if err == nil && n > limit {
+		err = errImageTooLarge
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,83 @@
+package assets
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/diagram.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png data"))
+		case "/render":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte("<svg></svg>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	d := NewDownloader(dir, "assets")
+
+	local, err := d.Download(server.URL + "/diagram.png")
+	if err != nil {
+		t.Fatalf("failed to download image: %v", err)
+	}
+	if !strings.HasPrefix(local, "assets/") || !strings.HasSuffix(local, ".png") {
+		t.Errorf("unexpected local path %q", local)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.Base(local)))
+	if err != nil || string(data) != "png data" {
+		t.Errorf("unexpected saved file: %q, %v", data, err)
+	}
+
+	svg, err := d.Download(server.URL + "/render?id=1")
+	if err != nil || !strings.HasSuffix(svg, ".svg") {
+		t.Errorf("expected .svg from Content-Type, got %q, %v", svg, err)
+	}
+
+	// Names are deterministic and existing files are not downloaded again
+	again, err := NewDownloader(dir, "assets").Download(server.URL + "/diagram.png")
+	if err != nil || again != local {
+		t.Errorf("expected same path %q, got %q, %v", local, again, err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+
+	if _, err := d.Download(server.URL + "/missing.png"); err == nil {
+		t.Error("expected error for missing image")
+	}
+	if _, err := d.Download("data:image/png;base64,AAAA"); err == nil {
+		t.Error("expected error for data URI")
+	}
+}
+
+func TestWriteFileLimit(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "image.png")
+	if err := writeFile(name, strings.NewReader("12345"), 4); !errors.Is(err, errImageTooLarge) {
+		t.Errorf("expected image too large error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected no file to be left, got %v", entries)
+	}
+
+	if err := writeFile(name, strings.NewReader("1234"), 4); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if data, err := os.ReadFile(name); err != nil || string(data) != "1234" {
+		t.Errorf("unexpected saved file: %q, %v", data, err)
+	}
+}
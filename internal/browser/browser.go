@@ -1,8 +1,10 @@
 package browser
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"time"
 )
 
 // Browser represents a web browser interface for fetching content
@@ -13,15 +15,67 @@ type Browser interface {
 	Fetch(url string) ([]byte, error)
 	// SetCleaningOptions sets the HTML cleaning options
 	SetCleaningOptions(*CleaningOptions)
+	// SetRenderOptions sets how the page is loaded before it is captured
+	SetRenderOptions(*RenderOptions)
 }
 
-// CleaningOptions configures what elements to remove from HTML
+// RenderOptions configures how a page is loaded. Backends that do not run
+// JavaScript ignore them.
+type RenderOptions struct {
+	Wait               time.Duration `json:"-" yaml:"-"`                                               // Time allowed for JavaScript to run before the DOM is captured
+	ComputedVisibility bool          `json:"computed_visibility,omitempty" yaml:"computed_visibility"` // Mark elements hidden by their computed style (Chrome only)
+	InlineFrames       bool          `json:"inline_frames,omitempty" yaml:"inline_frames"`             // Inline the content of same-origin iframes (Chrome only)
+	FrameDomains       string        `json:"-" yaml:"frame_domains"`                                   // Comma-separated domains whose iframes are inlined as well
+	ShadowDOM          bool          `json:"shadow_dom,omitempty" yaml:"shadow_dom"`                   // Serialize open shadow roots (Chrome only)
+	Timeout            time.Duration `json:"-" yaml:"-"`                                               // Time allowed for the whole fetch, Wait plus fetchTimeout when zero
+}
+
+// fetchTimeout bounds the real time a backend may take to load a page on top
+// of the time given to JavaScript
+const fetchTimeout = 60 * time.Second
+
+// DefaultRenderOptions returns the default rendering configuration
+func DefaultRenderOptions() *RenderOptions {
+	return &RenderOptions{
+		Wait: 5 * time.Second,
+	}
+}
+
+// timeout returns the time a backend may take to fetch a page
+func (o *RenderOptions) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return o.Wait + fetchTimeout
+}
+
+// output runs a backend command and returns its standard output. The command
+// is killed once the timeout is up, which is reported as ErrTimeout.
+func (o *RenderOptions) output(url, path string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout())
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, args...).Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, &NavigationError{URL: url, Kind: ErrTimeout}
+	}
+	return output, err
+}
+
+// CleaningOptions configures what elements to remove from HTML. The struct
+// tags name the options in API requests and the configuration file.
 type CleaningOptions struct {
-	KeepHeader    bool // Keep header elements if true
-	KeepFooter    bool // Keep footer elements if true
-	KeepNav       bool // Keep navigation elements if true
-	KeepStyles    bool // Keep inline and internal styles if true
-	KeepComments  bool // Keep HTML comments if true
+	KeepHeader         bool   `json:"keep_header,omitempty" yaml:"keep_header"`                   // Keep header elements if true
+	KeepFooter         bool   `json:"keep_footer,omitempty" yaml:"keep_footer"`                   // Keep footer elements if true
+	KeepNav            bool   `json:"keep_nav,omitempty" yaml:"keep_nav"`                         // Keep navigation elements if true
+	KeepStyles         bool   `json:"keep_styles,omitempty" yaml:"keep_styles"`                   // Keep inline and internal styles if true
+	KeepComments       bool   `json:"keep_comments,omitempty" yaml:"keep_comments"`               // Keep HTML comments if true
+	KeepHidden         bool   `json:"keep_hidden,omitempty" yaml:"keep_hidden"`                   // Keep elements that are not visible to readers if true
+	KeepRelativeURLs   bool   `json:"keep_relative_urls,omitempty" yaml:"keep_relative_urls"`     // Keep relative link and image URLs if true
+	KeepTrackingParams bool   `json:"keep_tracking_params,omitempty" yaml:"keep_tracking_params"` // Keep tracking parameters and redirect wrappers in links if true
+	Readability        bool   `json:"readability,omitempty" yaml:"readability"`                   // Keep only the main article content if true
+	Select             string `json:"select,omitempty" yaml:"select"`                             // Keep only subtrees matching this CSS selector if set
+	Remove             string `json:"remove,omitempty" yaml:"remove"`                             // Remove elements matching this CSS selector if set
 }
 
 // ExecutableFinder is an interface for finding browser executables
@@ -44,6 +98,28 @@ func (f *DefaultExecutableFinder) Find() (string, error) {
 	return "", fmt.Errorf("no browser executable found for: %v", f.names)
 }
 
+// FindAll returns every executable on PATH matching the finder names, in order
+func (f *DefaultExecutableFinder) FindAll() []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, name := range f.names {
+		path, err := exec.LookPath(name)
+		if err != nil || seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// browserExecutables lists the executable names searched for each backend
+var browserExecutables = map[string][]string{
+	"chrome":  {"google-chrome", "chromium", "chromium-browser"},
+	"firefox": {"firefox"},
+	"curl":    {"curl"},
+}
+
 // DefaultBrowsers defines the priority order for browsers
 var DefaultBrowsers = []string{"chrome", "firefox", "curl"}
 
@@ -60,6 +136,15 @@ func GetDefaultBrowser() (Browser, error) {
 	return nil, fmt.Errorf("no supported browsers found: %v", lastErr)
 }
 
+// IsSupported reports whether NewBrowser accepts the browser name
+func IsSupported(name string) bool {
+	switch name {
+	case "chrome", "chromium", "firefox", "curl":
+		return true
+	}
+	return false
+}
+
 // NewBrowser creates a new browser instance based on the browser name
 func NewBrowser(name string) (Browser, error) {
 	switch name {
@@ -1,8 +1,11 @@
 package browser
 
 import (
+	"errors"
+	"os/exec"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestBrowserRedirect(t *testing.T) {
@@ -34,3 +37,24 @@ func TestBrowserRedirect(t *testing.T) {
 		t.Error("Content does not appear to be HTML")
 	}
 }
+
+func TestRenderOptionsTimeout(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	opts := &RenderOptions{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err = opts.output("https://example.com", sleep, "5")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the command to be killed, took %s", elapsed)
+	}
+
+	if got := DefaultRenderOptions().timeout(); got != 5*time.Second+fetchTimeout {
+		t.Errorf("expected the default timeout to cover the wait, got %s", got)
+	}
+}
@@ -2,17 +2,17 @@ package browser
 
 import (
 	"fmt"
-	"os/exec"
 )
 
 type Chrome struct {
 	execPath string
 	cleaningOpts *CleaningOptions
+	renderOpts *RenderOptions
 }
 
 func NewChrome() (*Chrome, error) {
 	finder := &DefaultExecutableFinder{
-		names: []string{"google-chrome", "chromium", "chromium-browser"},
+		names: browserExecutables["chrome"],
 	}
 
 	path, err := finder.Find()
@@ -23,6 +23,7 @@ func NewChrome() (*Chrome, error) {
 	return &Chrome{
 		execPath: path,
 		cleaningOpts: DefaultCleaningOptions(),
+		renderOpts: DefaultRenderOptions(),
 	}, nil
 }
 
@@ -34,22 +35,54 @@ func (c *Chrome) SetCleaningOptions(opts *CleaningOptions) {
 	c.cleaningOpts = opts
 }
 
+func (c *Chrome) SetRenderOptions(opts *RenderOptions) {
+	c.renderOpts = opts
+}
+
 func (c *Chrome) Fetch(url string) ([]byte, error) {
+	// --dump-dom does not report redirects, so pages whose links are made
+	// absolute are captured through DevTools, which knows the final URL
+	if c.renderOpts.needsDevTools() || !c.cleaningOpts.KeepRelativeURLs {
+		output, finalURL, document, err := c.fetchWithDevTools(url)
+		if err != nil {
+			return nil, err
+		}
+		if document {
+			return output, nil
+		}
+		if isPDFViewer(output) {
+			return downloadPDF(finalURL)
+		}
+		if isFeedViewer(output) {
+			return downloadFeed(finalURL)
+		}
+		return CleanHTMLWithURL(output, finalURL, c.cleaningOpts), nil
+	}
+
 	// Use Chrome in headless mode to fetch content
-	cmd := exec.Command(c.execPath,
+	output, err := c.renderOpts.output(url, c.execPath,
 		"--headless",
 		"--disable-gpu",
 		"--no-sandbox",
 		"--enable-automation",
-		"--virtual-time-budget=5000",  // Allow 5 seconds for JavaScript execution
+		fmt.Sprintf("--virtual-time-budget=%d", c.renderOpts.Wait.Milliseconds()),  // Allow time for JavaScript execution
 		"--dump-dom",  // This will output the rendered DOM
 		url,
 	)
-
-	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("chrome execution error: %v", err)
+		return nil, fmt.Errorf("chrome execution error: %w", err)
+	}
+
+	if err := detectErrorPage(url, output); err != nil {
+		return nil, err
+	}
+	if isPDFViewer(output) {
+		return downloadPDF(url)
+	}
+	if isFeedViewer(output) {
+		return downloadFeed(url)
 	}
 
-	return CleanHTML(output, c.cleaningOpts), nil
+	// Links are kept as written, so the URL only matters for metadata
+	return CleanHTMLWithURL(output, url, c.cleaningOpts), nil
 }
@@ -1,18 +1,30 @@
 package browser
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathabonfim59/md-fetch/internal/converter"
+	"github.com/nathabonfim59/md-fetch/internal/feed"
 )
 
+// curlInfoMarker separates the response body from the --write-out details
+const curlInfoMarker = "\nmd-fetch-curl-info:"
+
 type Curl struct {
 	execPath string
 	cleaningOpts *CleaningOptions
+	renderOpts *RenderOptions
 }
 
 func NewCurl() (*Curl, error) {
 	finder := &DefaultExecutableFinder{
-		names: []string{"curl"},
+		names: browserExecutables["curl"],
 	}
 	
 	path, err := finder.Find()
@@ -23,6 +35,7 @@ func NewCurl() (*Curl, error) {
 	return &Curl{
 		execPath: path,
 		cleaningOpts: DefaultCleaningOptions(),
+		renderOpts: DefaultRenderOptions(),
 	}, nil
 }
 
@@ -34,12 +47,74 @@ func (c *Curl) SetCleaningOptions(opts *CleaningOptions) {
 	c.cleaningOpts = opts
 }
 
+// Curl does not run JavaScript, so only the timeout of the render options
+// applies
+func (c *Curl) SetRenderOptions(opts *RenderOptions) {
+	c.renderOpts = opts
+}
+
 func (c *Curl) Fetch(url string) ([]byte, error) {
-	cmd := exec.Command(c.execPath, "-L", "-s", url)
-	output, err := cmd.Output()
+	// curl exits with code 28 once either timeout is up, reported as
+	// ErrTimeout
+	timeout := c.renderOpts.timeout()
+	output, err := c.renderOpts.output(url, c.execPath, "-L", "-s",
+		"--connect-timeout", curlSeconds(min(timeout, curlConnectTimeout)),
+		"--max-time", curlSeconds(timeout),
+		"-w", curlInfoMarker+"%{http_code} %{url_effective} %{content_type}", url)
 	if err != nil {
-		return nil, fmt.Errorf("curl execution error: %v", err)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if navErr := curlExitError(url, exitErr.ExitCode()); navErr != nil {
+				return nil, navErr
+			}
+		}
+		return nil, fmt.Errorf("curl execution error: %w", err)
+	}
+
+	body, status, finalURL, contentType := splitCurlInfo(output)
+	if err := httpStatusError(url, status); err != nil {
+		return nil, err
+	}
+	if finalURL == "" {
+		finalURL = url
+	}
+	// Browsers render PDF documents and feeds in a viewer, curl hands over
+	// the document itself, which is not cleaned as HTML
+	if isPDFType(contentType) || converter.IsPDF(body) || feed.IsFeed(body) {
+		return body, nil
 	}
 
-	return CleanHTML(output, c.cleaningOpts), nil
+	return CleanHTMLWithURL(body, finalURL, c.cleaningOpts), nil
+}
+
+// curlConnectTimeout bounds the time curl may take to connect to a host
+const curlConnectTimeout = 30 * time.Second
+
+// curlSeconds formats a duration as the seconds curl timeout options take
+func curlSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// splitCurlInfo separates the response body from the HTTP status code, the
+// final URL after redirects and the Content-Type header written by
+// --write-out. File URLs report a status of 0 and no content type.
+func splitCurlInfo(output []byte) ([]byte, int, string, string) {
+	i := bytes.LastIndex(output, []byte(curlInfoMarker))
+	if i < 0 {
+		return output, 0, "", ""
+	}
+	// The content type is last, as it can hold spaces
+	info := strings.SplitN(strings.TrimSpace(string(output[i+len(curlInfoMarker):])), " ", 3)
+	var status int
+	var finalURL, contentType string
+	if len(info) > 0 {
+		status, _ = strconv.Atoi(info[0])
+	}
+	if len(info) > 1 {
+		finalURL = info[1]
+	}
+	if len(info) > 2 {
+		contentType = strings.TrimSpace(info[2])
+	}
+	return output[:i], status, finalURL, contentType
 }
@@ -0,0 +1,655 @@
+package browser
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// captureScript serializes the rendered page. It receives the capture options
+// as a JSON object and returns the document HTML along with the final URL. Unlike outerHTML it can mark
+// elements hidden by their computed style, replace allowed iframes with their
+// document and render open shadow roots with their slotted content.
+const captureScript = `(opts => {
+	const voidTags = new Set(['area', 'base', 'br', 'col', 'embed', 'hr', 'img', 'input', 'link', 'meta', 'source', 'track', 'wbr']);
+	const rawTags = new Set(['script', 'style', 'noscript', 'xmp', 'plaintext']);
+	const escapeText = s => s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+	const escapeAttr = s => s.replace(/&/g, '&amp;').replace(/"/g, '&quot;');
+
+	const isHidden = el => {
+		const view = el.ownerDocument.defaultView;
+		const style = view.getComputedStyle(el);
+		const rect = el.getBoundingClientRect();
+		const offscreen = rect.width > 0 && rect.height > 0 &&
+			(rect.right + view.scrollX <= 0 || rect.bottom + view.scrollY <= 0);
+		const clipped = rect.width <= 1 && rect.height <= 1 &&
+			style.overflow === 'hidden' && style.position === 'absolute';
+		return style.display === 'none' || style.visibility === 'hidden' ||
+			style.visibility === 'collapse' || offscreen || clipped;
+	};
+
+	const frameSource = frame => {
+		try {
+			return new URL(frame.src, frame.ownerDocument.baseURI);
+		} catch (e) {
+			return null;
+		}
+	};
+
+	// frameBody returns the body of a same-origin iframe
+	const frameBody = frame => {
+		const src = frameSource(frame);
+		if (!src || src.origin !== location.origin) {
+			return null;
+		}
+		try {
+			return frame.contentDocument && frame.contentDocument.body;
+		} catch (e) {
+			return null;
+		}
+	};
+
+	// isFrameDomain reports whether a cross-origin iframe belongs to one of
+	// the frame domains, whose document is read through its own DevTools
+	// session since the page cannot reach it
+	const isFrameDomain = frame => {
+		const src = frameSource(frame);
+		return src !== null && opts.frameDomains.some(domain =>
+			src.hostname === domain || src.hostname.endsWith('.' + domain));
+	};
+
+	const out = [];
+	let frameCount = 0;
+	// Only body content is marked hidden, head elements are never rendered
+	let inBody = false;
+	const serializeChildren = parent => {
+		for (const child of parent.childNodes) {
+			serialize(child);
+		}
+	};
+	const serialize = node => {
+		if (node.nodeType === Node.TEXT_NODE) {
+			const parent = node.parentNode && node.parentNode.localName;
+			out.push(rawTags.has(parent) ? node.data : escapeText(node.data));
+			return;
+		}
+		if (node.nodeType === Node.COMMENT_NODE) {
+			out.push('<!--' + node.data + '-->');
+			return;
+		}
+		if (node.nodeType !== Node.ELEMENT_NODE) {
+			return;
+		}
+
+		// An inlined iframe becomes a div, its content would be raw text
+		// inside an iframe element
+		const isFrame = opts.inlineFrames && node.localName === 'iframe';
+		const body = isFrame ? frameBody(node) : null;
+		let remote = null;
+		if (isFrame && !body && isFrameDomain(node)) {
+			remote = String(frameCount++);
+			node.setAttribute('` + frameIDAttr + `', remote);
+		}
+		const tag = body || remote !== null ? 'div' : node.localName;
+
+		const enteredBody = !inBody && node.localName === 'body';
+		if (enteredBody) {
+			inBody = true;
+		}
+
+		out.push('<' + tag);
+		for (const a of node.attributes) {
+			let value = a.value;
+			// Links inside inlined frames are relative to the frame document
+			if ((opts.frameDocument || node.ownerDocument !== document) && (a.name === 'href' || a.name === 'src')) {
+				try {
+					value = new URL(value, node.baseURI).href;
+				} catch (e) {}
+			}
+			out.push(' ' + a.name + '="' + escapeAttr(value) + '"');
+		}
+		if (body || remote !== null) {
+			out.push(' ` + frameAttr + `=""');
+		}
+		if (opts.markHidden && inBody && !enteredBody && isHidden(node)) {
+			out.push(' ` + hiddenAttr + `=""');
+		}
+		out.push('>');
+		if (voidTags.has(tag)) {
+			return;
+		}
+
+		if (body) {
+			serializeChildren(body);
+		} else if (remote !== null) {
+			out.push('<!--` + frameMarker + `' + remote + '-->');
+		} else if (tag === 'template') {
+			serializeChildren(node.content);
+		} else if (opts.shadowDOM && node.shadowRoot) {
+			serializeChildren(node.shadowRoot);
+		} else if (opts.shadowDOM && tag === 'slot' && node.assignedNodes().length > 0) {
+			node.assignedNodes().forEach(serialize);
+		} else {
+			serializeChildren(node);
+		}
+		out.push('</' + tag + '>');
+		if (enteredBody) {
+			inBody = false;
+		}
+	};
+
+	// A frame document only contributes its body to the page
+	if (opts.frameDocument) {
+		inBody = true;
+		serializeChildren(document.body || document.documentElement);
+		return {url: location.href, html: out.join('')};
+	}
+	if (document.doctype) {
+		out.push('<!DOCTYPE ' + document.doctype.name + '>');
+	}
+	serialize(document.documentElement);
+	return {url: location.href, html: out.join('')};
+})(%s)`
+
+// frameAttr marks the elements that replaced an inlined iframe
+const frameAttr = "data-md-fetch-frame"
+
+// frameIDAttr numbers the cross-origin iframes captureScript left for their
+// own DevTools session, and frameMarker is the comment standing in for their
+// document in the captured page
+const (
+	frameIDAttr = "data-md-fetch-frame-id"
+	frameMarker = "md-fetch-frame:"
+)
+
+// captureOptions configures captureScript
+type captureOptions struct {
+	MarkHidden   bool     `json:"markHidden"`
+	InlineFrames bool     `json:"inlineFrames"`
+	FrameDomains []string `json:"frameDomains"`
+	ShadowDOM    bool     `json:"shadowDOM"`
+	// FrameDocument captures the body of a frame target instead of a page
+	FrameDocument bool `json:"frameDocument"`
+}
+
+// devToolsMessage is a DevTools protocol command, response or event
+type devToolsMessage struct {
+	ID        int             `json:"id,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    interface{}     `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// devTools is a minimal DevTools protocol client talking to Chrome over
+// --remote-debugging-pipe, where Chrome reads commands from file descriptor 3
+// and writes responses and events to file descriptor 4, each message
+// terminated by a NUL byte
+type devTools struct {
+	cmd      *exec.Cmd
+	commands *os.File
+	messages chan devToolsMessage
+	events   map[string]bool
+	// frames maps the target ID of attached out-of-process frames, which is
+	// also their frame ID, to their session
+	frames map[string]string
+	// mainFrame is the frame of the page, status the HTTP status of its
+	// document once received
+	mainFrame string
+	status    int
+	nextID    int
+	// ctx ends when the session runs out of time, done once it is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startDevTools(execPath string, timeout time.Duration, extraArgs ...string) (*devTools, error) {
+	chromeIn, commands, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	responses, chromeOut, err := os.Pipe()
+	if err != nil {
+		chromeIn.Close()
+		commands.Close()
+		return nil, err
+	}
+
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--no-sandbox",
+		"--enable-automation",
+		"--remote-debugging-pipe",
+	}
+	args = append(args, extraArgs...)
+	cmd := exec.Command(execPath, append(args, "about:blank")...)
+	cmd.ExtraFiles = []*os.File{chromeIn, chromeOut}
+	if err := cmd.Start(); err != nil {
+		chromeIn.Close()
+		chromeOut.Close()
+		commands.Close()
+		responses.Close()
+		return nil, fmt.Errorf("chrome execution error: %v", err)
+	}
+	chromeIn.Close()
+	chromeOut.Close()
+
+	dt := &devTools{
+		cmd:      cmd,
+		commands: commands,
+		messages: make(chan devToolsMessage, 64),
+		events:   make(map[string]bool),
+		frames:   make(map[string]string),
+		done:     make(chan struct{}),
+	}
+	dt.ctx, dt.cancel = context.WithTimeout(context.Background(), timeout)
+
+	go func() {
+		defer close(dt.messages)
+		defer responses.Close()
+		reader := bufio.NewReader(responses)
+		for {
+			data, err := reader.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			var msg devToolsMessage
+			if json.Unmarshal(data[:len(data)-1], &msg) != nil {
+				continue
+			}
+			// Nobody reads the events that arrive after the session is
+			// closed
+			select {
+			case dt.messages <- msg:
+			case <-dt.done:
+				return
+			}
+		}
+	}()
+
+	return dt, nil
+}
+
+// call sends a command and waits for its response, recording events seen in
+// the meantime
+func (dt *devTools) call(sessionID, method string, params interface{}, result interface{}) error {
+	dt.nextID++
+	id := dt.nextID
+
+	data, err := json.Marshal(devToolsMessage{ID: id, SessionID: sessionID, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if _, err := dt.commands.Write(append(data, 0)); err != nil {
+		return fmt.Errorf("devtools %s: %v", method, err)
+	}
+
+	for {
+		msg, err := dt.next()
+		if err != nil {
+			return fmt.Errorf("devtools %s: %v", method, err)
+		}
+		if msg.ID != id {
+			continue
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("devtools %s: %s", method, msg.Error.Message)
+		}
+		if result != nil {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	}
+}
+
+// waitEvent blocks until the event has been received
+func (dt *devTools) waitEvent(method string) error {
+	for !dt.events[method] {
+		if _, err := dt.next(); err != nil {
+			return fmt.Errorf("waiting for %s: %v", method, err)
+		}
+	}
+	return nil
+}
+
+func (dt *devTools) next() (devToolsMessage, error) {
+	select {
+	case msg, ok := <-dt.messages:
+		if !ok {
+			return msg, fmt.Errorf("chrome closed the connection")
+		}
+		if msg.Method != "" {
+			dt.events[msg.Method] = true
+		}
+		switch msg.Method {
+		case "Target.attachedToTarget":
+			dt.recordFrame(msg.Params)
+		case "Network.responseReceived":
+			dt.recordResponse(msg.Params)
+		}
+		return msg, nil
+	case <-dt.ctx.Done():
+		return devToolsMessage{}, fmt.Errorf("timed out")
+	}
+}
+
+// recordFrame remembers the session of an auto-attached frame target
+func (dt *devTools) recordFrame(params interface{}) {
+	p, _ := params.(map[string]interface{})
+	info, _ := p["targetInfo"].(map[string]interface{})
+	sessionID, _ := p["sessionId"].(string)
+	targetID, _ := info["targetId"].(string)
+	if kind, _ := info["type"].(string); kind == "iframe" && targetID != "" {
+		dt.frames[targetID] = sessionID
+	}
+}
+
+// recordResponse remembers the status of the document of the main frame.
+// Redirects are not reported as responses, so this is the final status.
+func (dt *devTools) recordResponse(params interface{}) {
+	p, _ := params.(map[string]interface{})
+	response, _ := p["response"].(map[string]interface{})
+	kind, _ := p["type"].(string)
+	frameID, _ := p["frameId"].(string)
+	if status, ok := response["status"].(float64); ok && kind == "Document" && frameID == dt.mainFrame {
+		dt.status = int(status)
+	}
+}
+
+func (dt *devTools) close() {
+	dt.call("", "Browser.close", nil, nil)
+	dt.commands.Close()
+	close(dt.done)
+	dt.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		dt.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		dt.cmd.Process.Kill()
+		<-done
+	}
+}
+
+// fetchWithDevTools loads the page through the DevTools protocol and captures
+// it with captureScript, which can inspect the rendered page in ways
+// --dump-dom cannot. It returns the page HTML and its URL after redirects,
+// or the raw bytes of a PDF document or feed the page turned out to be, in
+// which case document is true.
+func (c *Chrome) fetchWithDevTools(url string) (page []byte, finalURL string, document bool, err error) {
+	frameDomains := c.renderOpts.frameDomains()
+
+	dt, err := startDevTools(c.execPath, c.renderOpts.timeout())
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer dt.close()
+
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := dt.call("", "Target.createTarget", map[string]interface{}{"url": "about:blank"}, &target); err != nil {
+		return nil, "", false, err
+	}
+
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := dt.call("", "Target.attachToTarget", map[string]interface{}{"targetId": target.TargetID, "flatten": true}, &session); err != nil {
+		return nil, "", false, err
+	}
+	s := session.SessionID
+
+	if err := dt.call(s, "Page.enable", nil, nil); err != nil {
+		return nil, "", false, err
+	}
+	// The main frame of a page target has the target's ID
+	dt.mainFrame = target.TargetID
+	if err := dt.call(s, "Network.enable", nil, nil); err != nil {
+		return nil, "", false, err
+	}
+
+	remoteFrames := c.renderOpts.InlineFrames && len(frameDomains) > 0
+	if remoteFrames {
+		// Site isolation runs cross-origin frames as targets of their own,
+		// attaching to them lets us read their document with the same origin
+		// policy left in place
+		if err := dt.call(s, "Target.setAutoAttach", map[string]interface{}{
+			"autoAttach":             true,
+			"waitForDebuggerOnStart": false,
+			"flatten":                true,
+		}, nil); err != nil {
+			return nil, "", false, err
+		}
+	}
+
+	// Same behaviour as --virtual-time-budget: let the page run until the
+	// budget is spent, pausing while network requests are pending. The budget
+	// only starts once the navigation below commits, otherwise a slow
+	// response could use it up on about:blank.
+	if err := dt.call(s, "Emulation.setVirtualTimePolicy", map[string]interface{}{
+		"policy":            "pauseIfNetworkFetchesPending",
+		"budget":            c.renderOpts.Wait.Milliseconds(),
+		"waitForNavigation": true,
+	}, nil); err != nil {
+		return nil, "", false, err
+	}
+
+	var navigation struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := dt.call(s, "Page.navigate", map[string]interface{}{"url": url}, &navigation); err != nil {
+		return nil, "", false, err
+	}
+	if navigation.ErrorText != "" {
+		return nil, "", false, chromeError(url, strings.TrimPrefix(navigation.ErrorText, "net::"))
+	}
+
+	if err := dt.waitEvent("Emulation.virtualTimeBudgetExpired"); err != nil {
+		return nil, "", false, fmt.Errorf("chrome execution error: %v", err)
+	}
+	if err := httpStatusError(url, dt.status); err != nil {
+		return nil, "", false, err
+	}
+
+	opts := captureOptions{
+		MarkHidden:   c.renderOpts.ComputedVisibility,
+		InlineFrames: c.renderOpts.InlineFrames,
+		FrameDomains: frameDomains,
+		ShadowDOM:    c.renderOpts.ShadowDOM,
+	}
+	html, finalURL, err := dt.capture(s, opts)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if remoteFrames && strings.Contains(html, "<!--"+frameMarker) {
+		html = dt.inlineRemoteFrames(s, html, opts)
+	}
+
+	// Documents shown in a viewer are read again from the page, so the
+	// request carries the cookies and headers of the browser
+	if isPDFViewer([]byte(html)) || isFeedViewer([]byte(html)) {
+		if document, err := dt.readDocument(s); err == nil {
+			return document, finalURL, true, nil
+		} else if errors.Is(err, ErrDocumentTooLarge) {
+			return nil, "", false, err
+		}
+	}
+
+	return []byte(html), finalURL, false, nil
+}
+
+// documentScript reads the response body of the current URL, up to the
+// size limit it receives, as base64
+const documentScript = `(async limit => {
+	const response = await fetch(location.href, {credentials: 'include'});
+	const reader = response.body.getReader();
+	let binary = '';
+	for (;;) {
+		const {done, value} = await reader.read();
+		if (done) {
+			break;
+		}
+		if (binary.length + value.length > limit) {
+			reader.cancel();
+			throw new Error('` + documentTooLargeText + `');
+		}
+		for (let i = 0; i < value.length; i += 0x8000) {
+			binary += String.fromCharCode.apply(null, value.subarray(i, i + 0x8000));
+		}
+	}
+	return btoa(binary);
+})(%d)`
+
+// documentTooLargeText is the error documentScript throws past the limit
+const documentTooLargeText = "md-fetch: document too large"
+
+// readDocument returns the raw bytes of the document open in the session
+func (dt *devTools) readDocument(sessionID string) ([]byte, error) {
+	var evaluation struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	if err := dt.call(sessionID, "Runtime.evaluate", map[string]interface{}{
+		"expression":    fmt.Sprintf(documentScript, maxDocumentSize),
+		"awaitPromise":  true,
+		"returnByValue": true,
+	}, &evaluation); err != nil {
+		return nil, err
+	}
+	if e := evaluation.ExceptionDetails; e != nil {
+		if strings.Contains(e.Exception.Description, documentTooLargeText) {
+			return nil, fmt.Errorf("failed to read document: %w", ErrDocumentTooLarge)
+		}
+		return nil, fmt.Errorf("chrome document script error: %s", e.Text)
+	}
+	return base64.StdEncoding.DecodeString(evaluation.Result.Value)
+}
+
+// capture runs captureScript in the session and returns the HTML and URL
+func (dt *devTools) capture(sessionID string, opts captureOptions) (string, string, error) {
+	captureOpts, err := json.Marshal(opts)
+	if err != nil {
+		return "", "", err
+	}
+
+	var evaluation struct {
+		Result struct {
+			Value struct {
+				URL  string `json:"url"`
+				HTML string `json:"html"`
+			} `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	if err := dt.call(sessionID, "Runtime.evaluate", map[string]interface{}{
+		"expression":    fmt.Sprintf(captureScript, captureOpts),
+		"returnByValue": true,
+	}, &evaluation); err != nil {
+		return "", "", err
+	}
+	if evaluation.ExceptionDetails != nil {
+		return "", "", fmt.Errorf("chrome capture script error: %s", evaluation.ExceptionDetails.Text)
+	}
+
+	return evaluation.Result.Value.HTML, evaluation.Result.Value.URL, nil
+}
+
+// domNode is the part of a DOM.Node needed to find iframe frame IDs
+type domNode struct {
+	FrameID         string    `json:"frameId"`
+	Attributes      []string  `json:"attributes"`
+	Children        []domNode `json:"children"`
+	ContentDocument *domNode  `json:"contentDocument"`
+	ShadowRoots     []domNode `json:"shadowRoots"`
+}
+
+// frameIDs maps the frameIDAttr numbers set by captureScript to frame IDs
+func (n *domNode) frameIDs(ids map[string]string) {
+	for i := 0; i+1 < len(n.Attributes); i += 2 {
+		if n.Attributes[i] == frameIDAttr && n.FrameID != "" {
+			ids[n.Attributes[i+1]] = n.FrameID
+		}
+	}
+	for i := range n.Children {
+		n.Children[i].frameIDs(ids)
+	}
+	for i := range n.ShadowRoots {
+		n.ShadowRoots[i].frameIDs(ids)
+	}
+	if n.ContentDocument != nil {
+		n.ContentDocument.frameIDs(ids)
+	}
+}
+
+// inlineRemoteFrames replaces the frame markers left by captureScript with
+// the body of the frame, captured in the frame's own session. Frames that
+// cannot be read are left empty.
+func (dt *devTools) inlineRemoteFrames(sessionID, html string, opts captureOptions) string {
+	var document struct {
+		Root domNode `json:"root"`
+	}
+	if err := dt.call(sessionID, "DOM.getDocument", map[string]interface{}{"depth": -1, "pierce": true}, &document); err != nil {
+		return html
+	}
+	ids := make(map[string]string)
+	document.Root.frameIDs(ids)
+
+	opts.InlineFrames = false
+	opts.FrameDocument = true
+	for number, frameID := range ids {
+		frameSession, ok := dt.frames[frameID]
+		if !ok {
+			continue
+		}
+		body, _, err := dt.capture(frameSession, opts)
+		if err != nil {
+			continue
+		}
+		html = strings.Replace(html, "<!--"+frameMarker+number+"-->", body, 1)
+	}
+	return html
+}
+
+// needsDevTools reports whether the render options require capturing the
+// page through the DevTools protocol instead of --dump-dom
+func (o *RenderOptions) needsDevTools() bool {
+	return o.ComputedVisibility || o.InlineFrames || o.ShadowDOM
+}
+
+// frameDomains splits FrameDomains into lower-cased domain names
+func (o *RenderOptions) frameDomains() []string {
+	domains := []string{}
+	for _, domain := range strings.Split(o.FrameDomains, ",") {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
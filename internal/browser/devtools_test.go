@@ -0,0 +1,150 @@
+package browser
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	if status := os.Getenv("MD_FETCH_FAKE_CHROME"); status != "" {
+		fakeChrome(status)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeChrome stands in for Chrome on the other end of the DevTools pipe,
+// serving a page with the given HTTP status
+func fakeChrome(status string) {
+	commands := bufio.NewReader(os.NewFile(3, "commands"))
+	messages := os.NewFile(4, "messages")
+	send := func(msg string) {
+		messages.Write(append([]byte(msg), 0))
+	}
+	for {
+		data, err := commands.ReadBytes(0)
+		if err != nil {
+			return
+		}
+		var msg devToolsMessage
+		if err := json.Unmarshal(data[:len(data)-1], &msg); err != nil {
+			return
+		}
+		reply := func(result string) {
+			send(`{"id":` + strconv.Itoa(msg.ID) + `,"result":` + result + `}`)
+		}
+		switch msg.Method {
+		case "Target.createTarget":
+			reply(`{"targetId":"T"}`)
+		case "Target.attachToTarget":
+			reply(`{"sessionId":"S"}`)
+		case "Page.navigate":
+			send(`{"method":"Network.responseReceived","sessionId":"S","params":{"type":"Document","frameId":"T","response":{"status":` + status + `}}}`)
+			reply(`{"frameId":"T"}`)
+			send(`{"method":"Emulation.virtualTimeBudgetExpired","sessionId":"S"}`)
+		case "Runtime.evaluate":
+			reply(`{"result":{"value":{"url":"http://example.com/","html":"<html><body><h1>Page status ` + status + `</h1></body></html>"}}}`)
+		case "Browser.close":
+			reply(`{}`)
+			return
+		default:
+			reply(`{}`)
+		}
+	}
+}
+
+func TestFetchWithDevToolsStatus(t *testing.T) {
+	t.Setenv("MD_FETCH_FAKE_CHROME", "404")
+	c := &Chrome{execPath: os.Args[0], cleaningOpts: DefaultCleaningOptions(), renderOpts: DefaultRenderOptions()}
+	if _, err := c.Fetch("http://example.com/"); !errors.Is(err, ErrHTTPStatus) {
+		t.Errorf("expected an HTTP status error for a 404 page, got %v", err)
+	}
+
+	t.Setenv("MD_FETCH_FAKE_CHROME", "200")
+	content, err := c.Fetch("http://example.com/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(content), "Page status 200") {
+		t.Errorf("expected the page in result:\n%s", content)
+	}
+}
+
+func TestRenderOptionsFrameDomains(t *testing.T) {
+	opts := &RenderOptions{FrameDomains: " Docs.Example.com, ,codepen.io "}
+	expected := []string{"docs.example.com", "codepen.io"}
+	if got := opts.frameDomains(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if DefaultRenderOptions().needsDevTools() {
+		t.Error("expected default render options to use --dump-dom")
+	}
+	for _, opts := range []*RenderOptions{{ComputedVisibility: true}, {InlineFrames: true}, {ShadowDOM: true}} {
+		if !opts.needsDevTools() {
+			t.Errorf("expected %+v to use the DevTools protocol", opts)
+		}
+	}
+}
+
+func TestCleanHTMLStripsFrameMarker(t *testing.T) {
+	content := `<html><body><div src="/embed" ` + frameAttr + `=""><p>Embedded docs</p></div></body></html>`
+	result := string(CleanHTML([]byte(content), DefaultCleaningOptions()))
+	if !strings.Contains(result, "Embedded docs") {
+		t.Errorf("expected inlined frame content in result:\n%s", result)
+	}
+	if strings.Contains(result, frameAttr) {
+		t.Errorf("expected frame marker to be stripped:\n%s", result)
+	}
+}
+
+func TestDOMNodeFrameIDs(t *testing.T) {
+	var document struct {
+		Root domNode `json:"root"`
+	}
+	data := `{"root":{"children":[{"attributes":["src","https://codepen.io/a","` + frameIDAttr + `","0"],"frameId":"F1"},
+		{"shadowRoots":[{"children":[{"attributes":["` + frameIDAttr + `","1"],"frameId":"F2"}]}]},
+		{"attributes":["src","/same"],"frameId":"F3","contentDocument":{"children":[{"attributes":["` + frameIDAttr + `","2"],"frameId":"F4"}]}}]}}`
+	if err := json.Unmarshal([]byte(data), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[string]string)
+	document.Root.frameIDs(ids)
+	expected := map[string]string{"0": "F1", "1": "F2", "2": "F4"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestDevToolsCloseStopsReader(t *testing.T) {
+	// A stand-in for Chrome that floods the pipe with events nobody reads
+	chrome := filepath.Join(t.TempDir(), "chrome")
+	script := "#!/bin/sh\nfor i in $(seq 500); do printf '{\"method\":\"Page.lifecycleEvent\"}\\000' >&4; done\nsleep 0.2\n"
+	if err := os.WriteFile(chrome, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	before := runtime.NumGoroutine()
+	dt, err := startDevTools(chrome, 100*time.Millisecond)
+	if err != nil {
+		t.Skipf("cannot run the test browser: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	dt.close()
+
+	for start := time.Now(); runtime.NumGoroutine() > before; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("expected the reader to stop, %d goroutines left of %d", runtime.NumGoroutine(), before)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// diagnoseTimeout bounds every command run while diagnosing a backend
+const diagnoseTimeout = 30 * time.Second
+
+// testPageMarker is the text the built-in test page must render to
+const testPageMarker = "md-fetch doctor test page"
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head><title>md-fetch doctor</title></head>
+<body>
+	<h1>` + testPageMarker + `</h1>
+	<p id="static">Static content</p>
+	<script>document.getElementById("static").textContent = "Rendered by JavaScript";</script>
+</body>
+</html>`
+
+// CheckStatus is the outcome of a single diagnostic check
+type CheckStatus int
+
+const (
+	CheckOK CheckStatus = iota
+	CheckWarn
+	CheckFail
+)
+
+func (s CheckStatus) String() string {
+	switch s {
+	case CheckOK:
+		return "ok"
+	case CheckWarn:
+		return "warn"
+	default:
+		return "FAIL"
+	}
+}
+
+// Check is the result of a single diagnostic probe
+type Check struct {
+	Name   string
+	Status CheckStatus
+	Detail string
+}
+
+// Diagnosis describes the health of a single backend executable
+type Diagnosis struct {
+	Browser  string   // Backend name as accepted by NewBrowser
+	Searched []string // Executable names looked up on PATH
+	Path     string   // Resolved executable, empty if none was found
+	Version  string   // First line of the executable's --version output
+	Default  bool     // Whether GetDefaultBrowser would pick this executable
+	Checks   []Check
+}
+
+// Healthy reports whether the executable was found and no check failed
+func (d *Diagnosis) Healthy() bool {
+	if d.Path == "" {
+		return false
+	}
+	for _, c := range d.Checks {
+		if c.Status == CheckFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Diagnose probes every executable DefaultExecutableFinder can locate for the
+// default backends, in priority order. Backends without any executable are
+// reported with an empty Path.
+func Diagnose() []Diagnosis {
+	var diagnoses []Diagnosis
+	foundDefault := false
+	for _, name := range DefaultBrowsers {
+		finder := &DefaultExecutableFinder{names: browserExecutables[name]}
+		paths := finder.FindAll()
+		if len(paths) == 0 {
+			diagnoses = append(diagnoses, Diagnosis{Browser: name, Searched: finder.names})
+			continue
+		}
+		for i, path := range paths {
+			d := diagnose(name, path)
+			d.Searched = finder.names
+			if !foundDefault && i == 0 {
+				d.Default = true
+				foundDefault = true
+			}
+			diagnoses = append(diagnoses, d)
+		}
+	}
+	return diagnoses
+}
+
+// diagnose runs every applicable check against a single executable
+func diagnose(name, path string) Diagnosis {
+	d := Diagnosis{Browser: name, Path: path}
+
+	if out, err := runProbe(path, "--version"); err == nil {
+		d.Version = firstLine(out)
+	}
+
+	d.Checks = append(d.Checks, checkPermissions(path))
+	switch name {
+	case "chrome":
+		d.Checks = append(d.Checks,
+			checkHeadless(path, "--headless", "--disable-gpu", "--no-sandbox", "--dump-dom", "about:blank"),
+			checkSandbox(path),
+		)
+	case "firefox":
+		d.Checks = append(d.Checks,
+			checkHeadless(path, "--headless", "--dump-dom", "about:blank"),
+		)
+	}
+	d.Checks = append(d.Checks, checkRender(newBrowserAt(name, path)))
+
+	return d
+}
+
+// newBrowserAt creates a backend bound to a specific executable
+func newBrowserAt(name, path string) Browser {
+	switch name {
+	case "chrome":
+		return &Chrome{execPath: path, cleaningOpts: DefaultCleaningOptions(), renderOpts: DefaultRenderOptions()}
+	case "firefox":
+		return &Firefox{execPath: path, cleaningOpts: DefaultCleaningOptions(), renderOpts: DefaultRenderOptions()}
+	default:
+		return &Curl{execPath: path, cleaningOpts: DefaultCleaningOptions(), renderOpts: DefaultRenderOptions()}
+	}
+}
+
+func checkPermissions(path string) Check {
+	check := Check{Name: "permissions"}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("cannot stat executable: %v", err)
+		return check
+	}
+	if info.Mode()&0111 == 0 {
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("%s is not executable (mode %s)", path, info.Mode())
+		return check
+	}
+
+	// Headless browsers need a writable temporary directory for their profile
+	f, err := os.CreateTemp("", "md-fetch-doctor-*")
+	if err != nil {
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("temporary directory %s is not writable: %v", os.TempDir(), err)
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	check.Detail = "executable and temporary directory writable"
+	return check
+}
+
+func checkHeadless(path string, args ...string) Check {
+	check := Check{Name: "headless"}
+	out, err := runProbe(path, args...)
+	switch {
+	case err != nil:
+		check.Status = CheckFail
+		check.Detail = err.Error()
+	case !strings.Contains(strings.ToLower(out), "<html"):
+		check.Status = CheckFail
+		check.Detail = "headless mode started but produced no DOM"
+	default:
+		check.Detail = "headless mode works"
+	}
+	return check
+}
+
+// checkSandbox runs Chrome with its sandbox enabled. md-fetch always passes
+// --no-sandbox, so a failure here is only a warning.
+func checkSandbox(path string) Check {
+	check := Check{Name: "sandbox"}
+	_, err := runProbe(path, "--headless", "--disable-gpu", "--dump-dom", "about:blank")
+	if err == nil {
+		check.Detail = "sandbox available"
+		return check
+	}
+
+	check.Status = CheckWarn
+	check.Detail = fmt.Sprintf("sandbox unavailable, md-fetch runs with --no-sandbox: %v", err)
+	if os.Geteuid() == 0 {
+		check.Detail += " (running as root)"
+	}
+	return check
+}
+
+// checkRender fetches the built-in test page through the backend and verifies
+// the cleaned output still contains it
+func checkRender(b Browser) Check {
+	check := Check{Name: "render"}
+
+	dir, err := os.MkdirTemp("", "md-fetch-doctor-*")
+	if err != nil {
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("cannot create test page: %v", err)
+		return check
+	}
+	defer os.RemoveAll(dir)
+
+	page := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(page, []byte(testPage), 0644); err != nil {
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("cannot create test page: %v", err)
+		return check
+	}
+
+	// The backend command is killed if it hangs, rather than left running
+	opts := DefaultRenderOptions()
+	opts.Timeout = diagnoseTimeout
+	b.SetRenderOptions(opts)
+	content, err := b.Fetch("file://" + filepath.ToSlash(page))
+
+	switch {
+	case errors.Is(err, ErrTimeout):
+		check.Status = CheckFail
+		check.Detail = fmt.Sprintf("timed out after %s", diagnoseTimeout)
+	case err != nil:
+		check.Status = CheckFail
+		check.Detail = err.Error()
+	case len(strings.TrimSpace(string(content))) == 0:
+		check.Status = CheckFail
+		check.Detail = "empty output"
+	case !strings.Contains(string(content), testPageMarker):
+		check.Status = CheckFail
+		check.Detail = "output does not contain the test page"
+	case strings.Contains(string(content), "Rendered by JavaScript"):
+		check.Detail = "test page rendered with JavaScript"
+	default:
+		check.Detail = "test page rendered without JavaScript"
+	}
+	return check
+}
+
+// runProbe runs an executable with a timeout, returning stdout or an error
+// that carries the most relevant stderr line
+func runProbe(path string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnoseTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("timed out after %s", diagnoseTimeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if line := lastLine(exitErr.Stderr); line != "" {
+				return "", fmt.Errorf("%v: %s", err, line)
+			}
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// lastLine returns the last non-empty line of command output, which is where
+// browsers usually print their fatal error
+func lastLine(b []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	line := strings.TrimSpace(lines[len(lines)-1])
+	if len(line) > 200 {
+		line = line[:200] + "..."
+	}
+	return line
+}
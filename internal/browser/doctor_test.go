@@ -0,0 +1,61 @@
+package browser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFindAll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake executables require a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"chromium", "chromium-browser"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("failed to create fake executable: %v", err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	finder := &DefaultExecutableFinder{names: browserExecutables["chrome"]}
+	paths := finder.FindAll()
+
+	expected := []string{filepath.Join(dir, "chromium"), filepath.Join(dir, "chromium-browser")}
+	if len(paths) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("expected %q at position %d, got %q", expected[i], i, paths[i])
+		}
+	}
+}
+
+func TestDiagnoseCurl(t *testing.T) {
+	path, err := exec.LookPath("curl")
+	if err != nil {
+		t.Skipf("curl not available: %v", err)
+	}
+
+	d := diagnose("curl", path)
+	if d.Version == "" {
+		t.Error("expected curl version to be reported")
+	}
+	if !d.Healthy() {
+		t.Errorf("expected curl to be healthy, got checks: %+v", d.Checks)
+	}
+
+	var rendered bool
+	for _, c := range d.Checks {
+		if c.Name == "render" && c.Status == CheckOK {
+			rendered = true
+		}
+	}
+	if !rendered {
+		t.Errorf("expected render check to pass, got checks: %+v", d.Checks)
+	}
+}
@@ -0,0 +1,81 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/nathabonfim59/md-fetch/internal/converter"
+	"github.com/nathabonfim59/md-fetch/internal/feed"
+)
+
+// maxDocumentSize bounds the size of PDF documents and feeds read again after
+// a browser showed them in a viewer
+const maxDocumentSize = 50 << 20
+
+// ErrDocumentTooLarge is returned for documents larger than maxDocumentSize
+var ErrDocumentTooLarge = fmt.Errorf("document larger than %d MB", maxDocumentSize>>20)
+
+// isPDFType reports whether a Content-Type header names a PDF document
+func isPDFType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/pdf"
+}
+
+// isPDFViewer reports whether a rendered DOM is the PDF viewer of Chrome,
+// an embed of the whole document, or the pdf.js viewer of Firefox. The
+// DOM of a viewer holds none of the text of the document.
+func isPDFViewer(dom []byte) bool {
+	if bytes.Contains(dom, []byte("resource://pdf.js")) {
+		return true
+	}
+	return len(dom) < 2048 && bytes.Contains(dom, []byte("<embed")) && bytes.Contains(dom, []byte(`type="application/pdf"`))
+}
+
+// isFeedViewer reports whether a rendered DOM is an RSS or Atom feed, shown
+// by the XML viewer of Chrome or as the parsed XML, which loses the markup
+// of entry contents
+func isFeedViewer(dom []byte) bool {
+	return bytes.Contains(dom, []byte("webkit-xml-viewer-source-xml")) || feed.IsFeed(dom)
+}
+
+// downloadPDF downloads the document a browser opened in its PDF viewer
+func downloadPDF(url string) ([]byte, error) {
+	return download(url, "PDF", converter.IsPDF)
+}
+
+// downloadFeed downloads the feed a browser rendered as XML
+func downloadFeed(url string) ([]byte, error) {
+	return download(url, "feed", feed.IsFeed)
+}
+
+// download fetches a document browsers do not render as HTML, checking
+// that the response is of the expected kind. It is used by backends that
+// cannot hand over the bytes they loaded, so the request does not carry
+// their cookies or user agent.
+func download(url, kind string, valid func([]byte) bool) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", kind, err)
+	}
+	defer resp.Body.Close()
+
+	if err := httpStatusError(url, resp.StatusCode); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", kind, err)
+	}
+	if len(body) > maxDocumentSize {
+		return nil, fmt.Errorf("failed to download %s from %s: %w", kind, url, ErrDocumentTooLarge)
+	}
+	if !valid(body) {
+		return nil, fmt.Errorf("failed to download %s: %s did not return a %s", kind, url, kind)
+	}
+	return body, nil
+}
@@ -0,0 +1,24 @@
+package browser
+
+import "testing"
+
+func TestDocumentDetection(t *testing.T) {
+	if !isPDFType("application/pdf") || !isPDFType("Application/PDF; qs=0.001") || isPDFType("text/html; charset=utf-8") {
+		t.Error("expected only the PDF content type to be recognized")
+	}
+
+	chrome := `<html><head></head><body style="height: 100%; width: 100%; overflow: hidden; margin:0px; background-color: rgb(38, 38, 38);"><embed name="5A3C" style="position:absolute; left: 0; top: 0;" width="100%" height="100%" src="about:blank" type="application/pdf" internalid="5A3C"></body></html>`
+	firefox := `<html dir="ltr" mozdisallowselectionprint=""><head><link rel="resource" type="application/l10n" href="resource://pdf.js/web/locale/locale.json"></head><body><div id="outerContainer"></div></body></html>`
+	page := `<html><body><article><p>The report:</p><embed src="/report.pdf" type="application/pdf"></article></body></html>`
+	if !isPDFViewer([]byte(chrome)) || !isPDFViewer([]byte(firefox)) {
+		t.Error("expected the Chrome and Firefox PDF viewers to be recognized")
+	}
+	if isPDFViewer([]byte(page + string(make([]byte, 2048)))) {
+		t.Error("expected a page embedding a PDF not to be taken for a viewer")
+	}
+
+	feed := []byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title><link>https://example.com/</link></channel></rss>`)
+	if !isFeedViewer(feed) || !isFeedViewer([]byte(`<rss><div id="webkit-xml-viewer-source-xml"></div></rss>`)) || isFeedViewer([]byte(page)) {
+		t.Error("expected only feeds to be recognized")
+	}
+}
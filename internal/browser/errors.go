@@ -0,0 +1,263 @@
+package browser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Navigation failure kinds. NavigationError unwraps to one of these, so callers
+// can test for them with errors.Is.
+var (
+	ErrDNS               = errors.New("DNS resolution failed")
+	ErrTLS               = errors.New("TLS error")
+	ErrConnectionRefused = errors.New("connection refused")
+	ErrTimeout           = errors.New("navigation timed out")
+	ErrHTTPStatus        = errors.New("HTTP error status")
+	ErrUnreachable       = errors.New("site cannot be reached")
+)
+
+// NavigationError reports a page that could not be loaded
+type NavigationError struct {
+	URL    string
+	Kind   error  // One of the Err* navigation failure kinds
+	Code   string // Backend specific code, e.g. ERR_NAME_NOT_RESOLVED or curl exit 6
+	Status int    // HTTP status code, set when Kind is ErrHTTPStatus
+}
+
+func (e *NavigationError) Error() string {
+	msg := e.Kind.Error()
+	if e.Status != 0 {
+		msg = fmt.Sprintf("%s %d", msg, e.Status)
+	}
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.URL, msg)
+}
+
+func (e *NavigationError) Unwrap() error {
+	return e.Kind
+}
+
+// httpStatusError returns a NavigationError for HTTP error statuses, or nil
+func httpStatusError(url string, status int) error {
+	if status < 400 {
+		return nil
+	}
+	return &NavigationError{URL: url, Kind: ErrHTTPStatus, Status: status}
+}
+
+// curlExitKinds maps curl exit codes to navigation failure kinds
+var curlExitKinds = map[int]error{
+	6:  ErrDNS,               // Couldn't resolve host
+	7:  ErrConnectionRefused, // Failed to connect to host
+	28: ErrTimeout,           // Operation timeout
+	35: ErrTLS,               // SSL connect error
+	51: ErrTLS,               // Peer certificate cannot be authenticated
+	53: ErrTLS,               // SSL crypto engine not found
+	54: ErrTLS,               // Cannot set SSL crypto engine as default
+	58: ErrTLS,               // Problem with the local certificate
+	59: ErrTLS,               // Couldn't use specified SSL cipher
+	60: ErrTLS,               // Peer certificate cannot be authenticated with known CA certificates
+	66: ErrTLS,               // Failed to initialise SSL engine
+	77: ErrTLS,               // Problem with reading the SSL CA cert
+	80: ErrTLS,               // Failed to shut down the SSL connection
+	83: ErrTLS,               // Issuer check failed
+	90: ErrTLS,               // SSL public key does not match pinned public key
+	91: ErrTLS,               // Invalid SSL certificate status
+}
+
+// curlExitError maps a curl exit code to a NavigationError, or nil if the
+// exit code is not a navigation failure
+func curlExitError(url string, exitCode int) error {
+	kind, ok := curlExitKinds[exitCode]
+	if !ok {
+		return nil
+	}
+	return &NavigationError{URL: url, Kind: kind, Code: "curl exit " + strconv.Itoa(exitCode)}
+}
+
+var (
+	chromeHTTPErrorPattern = regexp.MustCompile(`HTTP ERROR (\d{3})`)
+	chromeErrorCodePattern = regexp.MustCompile(`(?:NET::)?ERR_[A-Z0-9_]+|DNS_PROBE_[A-Z0-9_]+`)
+	chromeLoadTimePattern  = regexp.MustCompile(`"errorCode"\s*:\s*"([^"]+)"`)
+)
+
+// detectErrorPage inspects a rendered DOM for the built-in error pages of
+// Chrome and Firefox. Detection relies on the structure and error codes of
+// those pages rather than their text, which is localized.
+func detectErrorPage(url string, content []byte) error {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	if code, ok := chromeErrorCode(doc); ok {
+		return chromeError(url, code)
+	}
+	if code, ok := firefoxErrorCode(doc); ok {
+		return firefoxError(url, code)
+	}
+	return nil
+}
+
+// chromeErrorCode returns the error code of a Chrome network error or
+// certificate interstitial page
+func chromeErrorCode(doc *html.Node) (string, bool) {
+	isErrorPage := false
+	var candidates []string
+
+	walk(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch {
+		case n.Data == "body" && hasClass(n, "neterror"):
+			isErrorPage = true
+		case attr(n, "id") == "main-frame-error":
+			isErrorPage = true
+		case hasClass(n, "error-code") || attr(n, "id") == "error-code":
+			candidates = append(candidates, textContent(n))
+		case n.Data == "script":
+			if m := chromeLoadTimePattern.FindStringSubmatch(textContent(n)); m != nil {
+				candidates = append(candidates, m[1])
+			}
+		}
+	})
+
+	if !isErrorPage {
+		return "", false
+	}
+	for _, c := range candidates {
+		if m := chromeHTTPErrorPattern.FindString(c); m != "" {
+			return m, true
+		}
+		if m := chromeErrorCodePattern.FindString(c); m != "" {
+			return m, true
+		}
+	}
+	return "", true
+}
+
+func chromeError(url, code string) error {
+	e := &NavigationError{URL: url, Code: code, Kind: ErrUnreachable}
+	if m := chromeHTTPErrorPattern.FindStringSubmatch(code); m != nil {
+		e.Kind = ErrHTTPStatus
+		e.Status, _ = strconv.Atoi(m[1])
+		e.Code = ""
+		return e
+	}
+
+	c := strings.TrimPrefix(code, "NET::")
+	switch {
+	case strings.HasPrefix(c, "DNS_PROBE_"),
+		c == "ERR_NAME_NOT_RESOLVED",
+		c == "ERR_NAME_RESOLUTION_FAILED":
+		e.Kind = ErrDNS
+	case strings.HasPrefix(c, "ERR_CERT_"),
+		strings.HasPrefix(c, "ERR_SSL_"),
+		strings.HasPrefix(c, "ERR_BAD_SSL_"):
+		e.Kind = ErrTLS
+	case c == "ERR_CONNECTION_REFUSED":
+		e.Kind = ErrConnectionRefused
+	case c == "ERR_TIMED_OUT", c == "ERR_CONNECTION_TIMED_OUT":
+		e.Kind = ErrTimeout
+	}
+	return e
+}
+
+// firefoxErrorCode returns the error code of a Firefox about:neterror or
+// about:certerror page
+func firefoxErrorCode(doc *html.Node) (string, bool) {
+	isErrorPage := false
+	code := ""
+
+	walk(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		id := attr(n, "id")
+		if id == "errorPageContainer" || id == "errorTitleText" {
+			isErrorPage = true
+		}
+		if id == "errorTitleText" && code == "" {
+			code = strings.TrimSuffix(attr(n, "data-l10n-id"), "-title")
+		}
+		for _, a := range n.Attr {
+			if strings.Contains(a.Val, "aboutNetError") || strings.Contains(a.Val, "aboutCertError") {
+				isErrorPage = true
+			}
+			if i := strings.Index(a.Val, "about:neterror?"); i >= 0 && code == "" {
+				if q, err := url.ParseQuery(a.Val[i+len("about:neterror?"):]); err == nil {
+					code = q.Get("e")
+				}
+			}
+			if strings.Contains(a.Val, "about:certerror") && code == "" {
+				code = "certerror"
+			}
+		}
+	})
+
+	return code, isErrorPage
+}
+
+func firefoxError(url, code string) error {
+	e := &NavigationError{URL: url, Code: code, Kind: ErrUnreachable}
+	switch {
+	case code == "dnsNotFound":
+		e.Kind = ErrDNS
+	case code == "connectionFailure":
+		e.Kind = ErrConnectionRefused
+	case code == "netTimeout":
+		e.Kind = ErrTimeout
+	case strings.HasPrefix(code, "nss"),
+		strings.HasPrefix(code, "cert"),
+		strings.HasPrefix(code, "ssl"),
+		code == "inadequateSecurityError":
+		e.Kind = ErrTLS
+	}
+	return e
+}
+
+// walk calls fn for n and every descendant in document order
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	})
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,176 @@
+package browser
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDetectErrorPage(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		kind   error
+		status int
+		code   string
+	}{
+		{
+			name: "chrome dns failure",
+			input: `<html><body class="neterror"><div id="main-frame-error" class="interstitial-wrapper">
+				<h1>This site can't be reached</h1>
+				<div class="error-code">DNS_PROBE_FINISHED_NXDOMAIN</div>
+			</div></body></html>`,
+			kind: ErrDNS,
+			code: "DNS_PROBE_FINISHED_NXDOMAIN",
+		},
+		{
+			name: "localized chrome connection refused",
+			input: `<html lang="pt-BR"><body class="neterror"><div id="main-frame-error">
+				<h1>Não é possível acessar esse site</h1>
+				<div class="error-code">ERR_CONNECTION_REFUSED</div>
+			</div></body></html>`,
+			kind: ErrConnectionRefused,
+			code: "ERR_CONNECTION_REFUSED",
+		},
+		{
+			name: "chrome error code from load time data",
+			input: `<html><head><script>var loadTimeDataRaw = {"errorCode":"ERR_CONNECTION_TIMED_OUT"};</script></head>
+				<body class="neterror"><div id="main-frame-error"></div></body></html>`,
+			kind: ErrTimeout,
+			code: "ERR_CONNECTION_TIMED_OUT",
+		},
+		{
+			name: "chrome certificate error",
+			input: `<html><body class="neterror"><div class="interstitial-wrapper">
+				<div id="error-code">NET::ERR_CERT_AUTHORITY_INVALID</div>
+			</div></body></html>`,
+			kind: ErrTLS,
+			code: "NET::ERR_CERT_AUTHORITY_INVALID",
+		},
+		{
+			name: "chrome http error",
+			input: `<html><body class="neterror"><div id="main-frame-error">
+				<div class="error-code">HTTP ERROR 404</div>
+			</div></body></html>`,
+			kind:   ErrHTTPStatus,
+			status: 404,
+		},
+		{
+			name: "firefox dns failure",
+			input: `<html><head><link rel="stylesheet" href="chrome://browser/skin/aboutNetError.css"></head>
+				<body><div id="errorPageContainer">
+				<h1 id="errorTitleText" data-l10n-id="dnsNotFound-title">Hmm. We're having trouble finding that site.</h1>
+			</div></body></html>`,
+			kind: ErrDNS,
+			code: "dnsNotFound",
+		},
+		{
+			name: "firefox certificate error",
+			input: `<html><body><div id="errorPageContainer">
+				<h1 id="errorTitleText" data-l10n-id="nssBadCert-title">Aviso: Risco potencial de segurança</h1>
+			</div></body></html>`,
+			kind: ErrTLS,
+			code: "nssBadCert",
+		},
+		{
+			name: "firefox error code from document url",
+			input: `<html><body><div id="errorPageContainer">
+				<a href="about:neterror?e=netTimeout&amp;u=https%3A//example.com/">Try again</a>
+			</div></body></html>`,
+			kind: ErrTimeout,
+			code: "netTimeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := detectErrorPage("https://example.com", []byte(tt.input))
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("expected error kind %v, got %v", tt.kind, err)
+			}
+
+			var navErr *NavigationError
+			if !errors.As(err, &navErr) {
+				t.Fatalf("expected *NavigationError, got %T", err)
+			}
+			if navErr.Status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, navErr.Status)
+			}
+			if navErr.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, navErr.Code)
+			}
+		})
+	}
+}
+
+func TestDetectErrorPageIgnoresContent(t *testing.T) {
+	input := `<html><body>
+		<h1>Troubleshooting</h1>
+		<p>If you see "This site can't be reached" or DNS_PROBE_FINISHED_NXDOMAIN, check your resolver.</p>
+		<code class="error-code">ERR_CONNECTION_REFUSED</code>
+	</body></html>`
+
+	if err := detectErrorPage("https://example.com", []byte(input)); err != nil {
+		t.Errorf("expected regular page to pass through, got %v", err)
+	}
+}
+
+func TestCurlErrors(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		kind     error
+	}{
+		{6, ErrDNS},
+		{7, ErrConnectionRefused},
+		{28, ErrTimeout},
+		{35, ErrTLS},
+		{60, ErrTLS},
+	}
+
+	for _, tt := range tests {
+		if err := curlExitError("https://example.com", tt.exitCode); !errors.Is(err, tt.kind) {
+			t.Errorf("exit code %d: expected %v, got %v", tt.exitCode, tt.kind, err)
+		}
+	}
+
+	if err := curlExitError("https://example.com", 3); err != nil {
+		t.Errorf("expected no navigation error for exit code 3, got %v", err)
+	}
+
+	body, status, finalURL, contentType := splitCurlInfo([]byte("<html></html>" + curlInfoMarker + "503 https://example.com/final text/html; charset=utf-8"))
+	if string(body) != "<html></html>" || status != 503 || finalURL != "https://example.com/final" || contentType != "text/html; charset=utf-8" {
+		t.Errorf("unexpected split result: %q, %d, %q, %q", body, status, finalURL, contentType)
+	}
+	if err := httpStatusError("https://example.com", status); !errors.Is(err, ErrHTTPStatus) {
+		t.Errorf("expected HTTP status error, got %v", err)
+	}
+	if err := httpStatusError("https://example.com", 200); err != nil {
+		t.Errorf("expected no error for status 200, got %v", err)
+	}
+}
+
+func TestCurlTimeout(t *testing.T) {
+	c, err := NewCurl()
+	if err != nil {
+		t.Skipf("curl is not available: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	c.SetRenderOptions(&RenderOptions{Timeout: time.Second})
+	// Either curl gives up with exit code 28 or the command is killed
+	if _, err := c.Fetch(server.URL); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+
+	if got := curlSeconds(1500 * time.Millisecond); got != "1.5" {
+		t.Errorf("expected 1.5 seconds, got %s", got)
+	}
+}
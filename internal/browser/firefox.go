@@ -2,17 +2,17 @@ package browser
 
 import (
 	"fmt"
-	"os/exec"
 )
 
 type Firefox struct {
 	execPath string
 	cleaningOpts *CleaningOptions
+	renderOpts *RenderOptions
 }
 
 func NewFirefox() (*Firefox, error) {
 	finder := &DefaultExecutableFinder{
-		names: []string{"firefox"},
+		names: browserExecutables["firefox"],
 	}
 	
 	path, err := finder.Find()
@@ -23,6 +23,7 @@ func NewFirefox() (*Firefox, error) {
 	return &Firefox{
 		execPath: path,
 		cleaningOpts: DefaultCleaningOptions(),
+		renderOpts: DefaultRenderOptions(),
 	}, nil
 }
 
@@ -34,20 +35,36 @@ func (f *Firefox) SetCleaningOptions(opts *CleaningOptions) {
 	f.cleaningOpts = opts
 }
 
+// Firefox has no equivalent of Chrome's virtual time budget, so the wait
+// time is not used
+func (f *Firefox) SetRenderOptions(opts *RenderOptions) {
+	f.renderOpts = opts
+}
+
 func (f *Firefox) Fetch(url string) ([]byte, error) {
 	// Use Firefox in headless mode to fetch content
-	cmd := exec.Command(f.execPath,
+	output, err := f.renderOpts.output(url, f.execPath,
 		"--headless",
 		"--enable-automation",
 		"--wait-for-browser",
 		"--dump-dom",  // This will output the rendered DOM
 		url,
 	)
-	
-	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("firefox execution error: %v", err)
+		return nil, fmt.Errorf("firefox execution error: %w", err)
+	}
+
+	if err := detectErrorPage(url, output); err != nil {
+		return nil, err
+	}
+	if isPDFViewer(output) {
+		return downloadPDF(url)
+	}
+	if isFeedViewer(output) {
+		return downloadFeed(url)
 	}
 
-	return CleanHTML(output, f.cleaningOpts), nil
+	// --dump-dom does not report redirects, so links are resolved against
+	// the requested URL
+	return CleanHTMLWithURL(output, url, f.cleaningOpts), nil
 }
@@ -3,7 +3,6 @@ package browser
 import (
 	"bytes"
 	"io"
-	"regexp"
 	"strings"
 
 	"golang.org/x/net/html"
@@ -12,11 +11,17 @@ import (
 // DefaultCleaningOptions returns the default cleaning configuration
 func DefaultCleaningOptions() *CleaningOptions {
 	return &CleaningOptions{
-		KeepHeader:    false,
-		KeepFooter:    false,
-		KeepNav:       false,
-		KeepStyles:    false,
-		KeepComments:  false,
+		KeepHeader:         false,
+		KeepFooter:         false,
+		KeepNav:            false,
+		KeepStyles:         false,
+		KeepComments:       false,
+		KeepHidden:         false,
+		KeepRelativeURLs:   false,
+		KeepTrackingParams: false,
+		Readability:        false,
+		Select:             "",
+		Remove:             "",
 	}
 }
 
@@ -25,88 +30,42 @@ func StripJavaScript(content []byte) []byte {
 	return CleanHTML(content, DefaultCleaningOptions())
 }
 
-// CleanHTML removes unwanted elements from HTML content based on options
+// CleanHTML removes unwanted elements from HTML content based on options.
+// Cleaning works on the parsed document tree: scripts, styles, comments and
+// event handler attributes are removed as nodes, while text content, including
+// code samples in <pre> blocks, is never rewritten. The <head> and JSON-LD
+// scripts are kept so page metadata can be read from the result, with
+// microdata and RDFa items moved into JSON-LD, and TeX scripts so formulas
+// can be converted.
 func CleanHTML(content []byte, opts *CleaningOptions) []byte {
-	// Convert content to string for easier regex operations
-	contentStr := string(content)
-
-	// Remove script tags and their content
-	scriptPattern := regexp.MustCompile(`(?is)<script.*?>.*?</script>`)
-	contentStr = scriptPattern.ReplaceAllString(contentStr, "")
-
-	// Remove style tags if not keeping styles
-	if !opts.KeepStyles {
-		stylePattern := regexp.MustCompile(`(?is)<style.*?>.*?</style>`)
-		contentStr = stylePattern.ReplaceAllString(contentStr, "")
-	}
+	return CleanHTMLWithURL(content, "", opts)
+}
 
-	// Remove comments if not keeping them
-	if !opts.KeepComments {
-		commentPattern := regexp.MustCompile(`(?is)<!--.*?-->`)
-		contentStr = commentPattern.ReplaceAllString(contentStr, "")
+// CleanHTMLWithURL cleans HTML content like CleanHTML and, unless
+// opts.KeepRelativeURLs is set, rewrites relative links and image sources
+// against pageURL, the address the content was finally loaded from.
+func CleanHTMLWithURL(content []byte, pageURL string, opts *CleaningOptions) []byte {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return content // Return original content if parsing fails
 	}
 
-	// Remove inline JavaScript attributes
-	jsAttrPattern := regexp.MustCompile(`(?i)(on\w+)="[^"]*"`)
-	contentStr = jsAttrPattern.ReplaceAllString(contentStr, "")
+	hoistJSONLD(doc)
+	resolveImages(doc)
+	hoistStructuredData(doc, pageURL)
 
-	// Remove various JavaScript patterns
-	jsPatterns := []*regexp.Regexp{
-		// Google-specific patterns
-		regexp.MustCompile(`(?s)var\s+_g\s*=\s*\{\s*kEI\s*:[^}]*\}\s*;`),
-		regexp.MustCompile(`(?s)var\s+google\s*=\s*\{\s*[^}]*\}\s*;`),
-		regexp.MustCompile(`(?s)google\.[a-zA-Z_$][0-9a-zA-Z_$]*\s*=\s*[^;]*;`),
-		regexp.MustCompile(`(?s)\(\s*function\s*\(\)\s*\{\s*var\s+a\s*=\s*window\.innerWidth[^}]*\}\s*\)\s*\(\)\s*;`),
-		regexp.MustCompile(`(?s)window\._cshid\s*&&[^;]*;`),
-		
-		// Anonymous function calls with window assignments
-		regexp.MustCompile(`(?s)\(\s*function\s*\(\)\s*\{\s*var\s+[a-zA-Z_$][0-9a-zA-Z_$]*\s*=\s*\{[^}]*\}\s*;[^}]*\}\s*\)\s*\(\s*\)\s*;`),
-		
-		// RLQ function calls
-		regexp.MustCompile(`(?s)\(\s*RLQ\s*=\s*window\.RLQ\s*\|\|\s*\[\]\s*\)\.push\s*\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)\s*;`),
-		
-		// Self-executing functions with call
-		regexp.MustCompile(`(?s)\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)\s*\.\s*call\s*\([^)]*\)\s*;`),
-		
-		// Self-executing function expressions
-		regexp.MustCompile(`(?s)\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)\s*\([^)]*\)\s*;?`),
-		
-		// Window property assignments
-		regexp.MustCompile(`(?m)^[ \t]*window\.[a-zA-Z_$][0-9a-zA-Z_$]*\s*=\s*[^;]*;`),
-		
-		// Document event listeners
-		regexp.MustCompile(`(?s)document\.[a-zA-Z_$][0-9a-zA-Z_$]*\.addEventListener\s*\([^)]*\)\s*;`),
-		
-		// Inline JSON-LD
-		regexp.MustCompile(`(?s)\{\s*"@context"\s*:\s*"https?:\\?/\\?/schema\.org"[^}]*\}`),
-		
-		// CSS definitions and styles
-		regexp.MustCompile(`(?m)^[ \t]*#[a-zA-Z][0-9a-zA-Z_-]*\s*\{[^}]*\}`),
-		regexp.MustCompile(`(?m)^[ \t]*\.[a-zA-Z][0-9a-zA-Z_-]*\s*\{[^}]*\}`),
-		regexp.MustCompile(`(?m)^[ \t]*[a-zA-Z][0-9a-zA-Z_-]*\s*\{[^}]*\}`),
-		regexp.MustCompile(`(?m)^[ \t]*@media[^{]*\{[^}]*\}`),
-		
-		// Variable declarations
-		regexp.MustCompile(`(?m)^[ \t]*var\s+[a-zA-Z_$][0-9a-zA-Z_$]*\s*=[^;]*;`),
-		
-		// Function declarations and expressions
-		regexp.MustCompile(`(?m)^[ \t]*function\s+[a-zA-Z_$][0-9a-zA-Z_$]*\s*\([^)]*\)\s*\{[^}]*\}`),
-		regexp.MustCompile(`\([^)]*\)\s*=>\s*\{[^}]*\}`),
-		regexp.MustCompile(`\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)`),
-		
-		// Comments
-		regexp.MustCompile(`(?m)^[ \t]*//.*$`),
-		regexp.MustCompile(`(?s)/\*.*?\*/`),
+	if !opts.KeepRelativeURLs && pageURL != "" {
+		absolutizeURLs(doc, pageURL)
 	}
 
-	for _, pattern := range jsPatterns {
-		contentStr = pattern.ReplaceAllString(contentStr, "")
+	if !opts.KeepHidden {
+		removeHidden(doc)
 	}
 
-	// Parse and clean the HTML
-	doc, err := html.Parse(strings.NewReader(contentStr))
-	if err != nil {
-		return content // Return original content if parsing fails
+	applySelectors(doc, opts)
+
+	if opts.Readability {
+		extractMainContent(doc)
 	}
 
 	var buf bytes.Buffer
@@ -121,14 +80,20 @@ func cleanNode(w io.Writer, n *html.Node, opts *CleaningOptions) bool {
 			return false
 		}
 
+		// Remove inline JavaScript event handlers and backend markers
+		removeEventHandlers(n)
+		removeAttr(n, hiddenAttr)
+		removeAttr(n, frameAttr)
+		removeAttr(n, frameIDAttr)
+
 		// Clean inline styles if not keeping them
 		if !opts.KeepStyles {
 			removeStyleAttr(n)
 		}
 
-		// Clean JavaScript URLs
+		// Clean JavaScript, redirect and tracking URLs
 		if n.Data == "a" {
-			cleanJavaScriptURLs(n)
+			cleanLinkURLs(n, opts)
 		}
 	}
 
@@ -144,9 +109,12 @@ func cleanNode(w io.Writer, n *html.Node, opts *CleaningOptions) bool {
 		}
 		io.WriteString(w, ">")
 	} else if n.Type == html.TextNode {
-		// Clean any remaining JavaScript-like content in text nodes
-		text := cleanTextContent(n.Data)
-		io.WriteString(w, html.EscapeString(text))
+		// Raw text elements such as <style> must not be escaped
+		if n.Parent != nil && n.Parent.Type == html.ElementNode && rawTextElements[n.Parent.Data] {
+			io.WriteString(w, n.Data)
+		} else {
+			io.WriteString(w, html.EscapeString(n.Data))
+		}
 	} else if n.Type == html.CommentNode && opts.KeepComments {
 		io.WriteString(w, "<!--"+n.Data+"-->")
 	}
@@ -166,47 +134,14 @@ func cleanNode(w io.Writer, n *html.Node, opts *CleaningOptions) bool {
 	return true
 }
 
-func cleanTextContent(text string) string {
-	// Remove any JavaScript-like content from text
-	patterns := []*regexp.Regexp{
-		// RLQ function calls
-		regexp.MustCompile(`\(\s*RLQ\s*=\s*window\.RLQ\s*\|\|\s*\[\]\s*\)\.push\s*\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)\s*;`),
-		
-		// JSON-LD data
-		regexp.MustCompile(`\{\s*"@context"\s*:\s*"https?:\\?/\\?/schema\.org"[^}]*\}`),
-		
-		// Self-executing functions with call
-		regexp.MustCompile(`\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)\s*\.\s*call\s*\([^)]*\)\s*;`),
-		
-		// Self-executing function expressions
-		regexp.MustCompile(`\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)\s*\([^)]*\)\s*;?`),
-		
-		// Variable declarations
-		regexp.MustCompile(`var\s+[a-zA-Z_$][0-9a-zA-Z_$]*\s*=[^;]*;`),
-		
-		// Function declarations
-		regexp.MustCompile(`function\s+[a-zA-Z_$][0-9a-zA-Z_$]*\s*\([^)]*\)\s*\{[^}]*\}`),
-		
-		// Window assignments
-		regexp.MustCompile(`window\.[a-zA-Z_$][0-9a-zA-Z_$]*\s*=\s*[^;]*;`),
-		
-		// Document event listeners
-		regexp.MustCompile(`document\.[a-zA-Z_$][0-9a-zA-Z_$]*\.addEventListener\s*\([^)]*\)\s*;`),
-		
-		// Inline function expressions
-		regexp.MustCompile(`\([^)]*\)\s*=>\s*\{[^}]*\}`),
-		regexp.MustCompile(`\(\s*function\s*\([^)]*\)\s*\{[^}]*\}\s*\)`),
-	}
-
-	for _, pattern := range patterns {
-		text = pattern.ReplaceAllString(text, "")
-	}
-
-	return text
-}
-
 func shouldSkipNode(n *html.Node, opts *CleaningOptions) bool {
 	switch n.Data {
+	case "script":
+		// JSON-LD is data rather than code and carries the page metadata,
+		// TeX scripts hold the source of formulas
+		return !isJSONLD(n) && !isTeXScript(n)
+	case "noscript":
+		return true
 	case "header":
 		return !opts.KeepHeader
 	case "footer":
@@ -219,24 +154,49 @@ func shouldSkipNode(n *html.Node, opts *CleaningOptions) bool {
 	return false
 }
 
-func removeStyleAttr(n *html.Node) {
+func removeEventHandlers(n *html.Node) {
+	for i := 0; i < len(n.Attr); i++ {
+		if strings.HasPrefix(strings.ToLower(n.Attr[i].Key), "on") {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			i--
+		}
+	}
+}
+
+func removeAttr(n *html.Node, key string) {
 	for i := 0; i < len(n.Attr); i++ {
-		if n.Attr[i].Key == "style" {
-			// Remove the style attribute
+		if n.Attr[i].Key == key {
 			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
 			i--
 		}
 	}
 }
 
-func cleanJavaScriptURLs(n *html.Node) {
+func removeStyleAttr(n *html.Node) {
+	removeAttr(n, "style")
+}
+
+func cleanLinkURLs(n *html.Node, opts *CleaningOptions) {
 	for i := 0; i < len(n.Attr); i++ {
-		if n.Attr[i].Key == "href" && strings.HasPrefix(strings.TrimSpace(strings.ToLower(n.Attr[i].Val)), "javascript:") {
+		if n.Attr[i].Key != "href" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(strings.ToLower(n.Attr[i].Val)), "javascript:") {
 			n.Attr[i].Val = "#"
+		} else if !opts.KeepTrackingParams {
+			n.Attr[i].Val = normalizeLink(n.Attr[i].Val)
 		}
 	}
 }
 
+// Elements whose text content is written without escaping
+var rawTextElements = map[string]bool{
+	"script":    true,
+	"style":     true,
+	"xmp":       true,
+	"plaintext": true,
+}
+
 // List of void elements that don't need closing tags
 var voidElements = map[string]bool{
 	"area":   true,
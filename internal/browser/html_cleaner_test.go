@@ -54,22 +54,26 @@ func TestStripJavaScript(t *testing.T) {
 				</head>
 				<body>
 					<div>Content</div>
-					{"@context":"https://schema.org","@type":"Article","name":"Test"}
-					(function(x) { console.log(x); })();
+					<script type="application/ld+json">{"@context":"https://schema.org","@type":"Article","name":"Test"}</script>
+					<script>(function(x) { console.log(x); })();</script>
 				</body>
 			</html>`,
-			contains: []string{"<div>Content</div>"},
+			// JSON-LD is data, kept in the head for metadata extraction
+			contains: []string{"<div>Content</div>", `<script type="application/ld+json">{"@context":"https://schema.org"`},
 			excludes: []string{
 				"RLQ",
 				"window.RLQ",
 				"mw.config",
 				"wgHostname",
-				"schema.org",
-				"@context",
 				"function",
 				"console.log",
 			},
 		},
+		{
+			name: "tex scripts",
+			input: `<p>Energy <script type="math/tex">E = mc^2</script> and <script type="math/tex; mode=display">a < b</script></p>`,
+			contains: []string{`<script type="math/tex">E = mc^2</script>`, `<script type="math/tex; mode=display">a < b</script>`},
+		},
 		{
 			name: "complex javascript patterns",
 			input: `<html>
@@ -86,7 +90,8 @@ func TestStripJavaScript(t *testing.T) {
 				</head>
 				<body>
 					<div>Content</div>
-					{"@context":"https://schema.org","@type":"Article","name":"Test"}
+					<script type="application/ld+json">{"@context":"https://schema.org","@type":"Article","name":"Test"}</script>
+					<noscript><img src="/pixel.gif"></noscript>
 					<script>
 						(function(){
 							var src='/images/test.png';
@@ -104,12 +109,36 @@ func TestStripJavaScript(t *testing.T) {
 				"addEventListener",
 				"document.body",
 				"onerror",
-				"schema.org",
-				"@context",
 				"#gbar",
 				"font-size",
 				"var src",
 				"onload",
+				"pixel.gif",
+			},
+		},
+		{
+			name: "prose and code samples are preserved",
+			input: `<html>
+				<body>
+					<p>Declare it with var x = 1; before the loop.</p>
+					<p>A rule such as p { margin: 0 } resets the spacing.</p>
+					<pre><code>function greet(name) { return "hi " + name; }
+window.greeting = greet("you");
+// call it on load
+document.body.addEventListener("load", greet);
+.card { padding: 4px; }</code></pre>
+					<p>Use (function() { setup(); })(); to run it immediately.</p>
+				</body>
+			</html>`,
+			contains: []string{
+				"var x = 1;",
+				"p { margin: 0 }",
+				"function greet(name) { return &#34;hi &#34; + name; }",
+				"window.greeting = greet(&#34;you&#34;);",
+				"// call it on load",
+				"document.body.addEventListener(&#34;load&#34;, greet);",
+				".card { padding: 4px; }",
+				"(function() { setup(); })();",
 			},
 		},
 	}
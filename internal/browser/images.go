@@ -0,0 +1,195 @@
+package browser
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attributes lazy-loading scripts keep the real image URL in
+var lazySrcAttrs = []string{"data-src", "data-lazy-src", "data-original", "data-lazy", "data-hi-res-src"}
+
+// Attributes holding responsive image candidates
+var srcsetAttrs = []string{"srcset", "data-srcset", "data-lazy-srcset"}
+
+// File names of transparent spacer images
+var spacerImages = map[string]bool{
+	"blank.gif":       true,
+	"blank.png":       true,
+	"spacer.gif":      true,
+	"pixel.gif":       true,
+	"pixel.png":       true,
+	"transparent.gif": true,
+	"transparent.png": true,
+	"1x1.gif":         true,
+	"1x1.png":         true,
+}
+
+// placeholderDataURILength is the size below which an inline data URI is
+// considered a placeholder rather than real image content
+const placeholderDataURILength = 1024
+
+// resolveImages gives every image the URL of its best candidate and drops
+// the placeholders and spacers left behind by lazy loading
+func resolveImages(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && c.Data == "img" {
+			if !resolveImage(c) {
+				n.RemoveChild(c)
+			}
+		} else {
+			resolveImages(c)
+		}
+		c = next
+	}
+}
+
+// resolveImage rewrites the src of an image, preferring the largest
+// responsive candidate, then the lazy-loading attributes, then the original
+// src. It reports whether the image is worth keeping.
+func resolveImage(img *html.Node) bool {
+	src := strings.TrimSpace(attr(img, "src"))
+	if isPlaceholderImage(src) {
+		src = ""
+	}
+	for _, key := range lazySrcAttrs {
+		if value := strings.TrimSpace(attr(img, key)); value != "" && !isPlaceholderImage(value) {
+			src = value
+			break
+		}
+	}
+
+	var candidates []imageCandidate
+	if img.Parent != nil && img.Parent.Type == html.ElementNode && img.Parent.Data == "picture" {
+		for s := img.Parent.FirstChild; s != nil; s = s.NextSibling {
+			if s.Type == html.ElementNode && s.Data == "source" {
+				if t := attr(s, "type"); t == "" || strings.HasPrefix(t, "image/") {
+					candidates = append(candidates, srcsetCandidates(s)...)
+				}
+			}
+		}
+	}
+	candidates = append(candidates, srcsetCandidates(img)...)
+	if best := bestCandidate(candidates); best != "" {
+		src = best
+	}
+
+	for _, key := range append(lazySrcAttrs, srcsetAttrs...) {
+		removeAttr(img, key)
+	}
+	removeAttr(img, "sizes")
+	if src == "" {
+		return false
+	}
+	setAttr(img, "src", src)
+	return !isTrackingPixel(img)
+}
+
+// isPlaceholderImage reports whether an image URL points to a placeholder,
+// such as a small inline data URI or a transparent spacer file
+func isPlaceholderImage(src string) bool {
+	if src == "" {
+		return true
+	}
+	if strings.HasPrefix(strings.ToLower(src), "data:") {
+		return len(src) < placeholderDataURILength
+	}
+	name := src
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	return spacerImages[strings.ToLower(path.Base(name))]
+}
+
+// imageCandidate is one entry of a srcset attribute
+type imageCandidate struct {
+	url         string
+	descriptors string
+	width       float64 // from a w descriptor, 0 if absent
+	density     float64 // from an x descriptor, 1 if absent
+}
+
+// srcsetCandidates parses the srcset attributes of an img or source element
+func srcsetCandidates(n *html.Node) []imageCandidate {
+	var candidates []imageCandidate
+	for _, key := range srcsetAttrs {
+		for _, c := range parseSrcset(attr(n, key)) {
+			if !isPlaceholderImage(c.url) {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
+
+// parseSrcset splits a srcset attribute into its candidates. URLs end at
+// whitespace, so commas inside them are kept.
+func parseSrcset(srcset string) []imageCandidate {
+	var candidates []imageCandidate
+	s := srcset
+	for {
+		s = strings.TrimLeft(s, " \t\n\r\f,")
+		if s == "" {
+			return candidates
+		}
+
+		end := strings.IndexAny(s, " \t\n\r\f")
+		if end < 0 {
+			end = len(s)
+		}
+		url, descriptors := s[:end], ""
+		s = s[end:]
+		if trimmed := strings.TrimRight(url, ","); trimmed != url {
+			url = trimmed
+		} else if i := strings.IndexByte(s, ','); i >= 0 {
+			descriptors, s = s[:i], s[i+1:]
+		} else {
+			descriptors, s = s, ""
+		}
+
+		c := imageCandidate{url: url, descriptors: strings.TrimSpace(descriptors), density: 1}
+		for _, d := range strings.Fields(descriptors) {
+			value, err := strconv.ParseFloat(d[:len(d)-1], 64)
+			if err != nil {
+				continue
+			}
+			switch d[len(d)-1] {
+			case 'w':
+				c.width = value
+			case 'x':
+				c.density = value
+			}
+		}
+		candidates = append(candidates, c)
+	}
+}
+
+// bestCandidate returns the URL of the widest candidate, or of the highest
+// density one when no widths are given
+func bestCandidate(candidates []imageCandidate) string {
+	var best *imageCandidate
+	for i := range candidates {
+		c := &candidates[i]
+		if best == nil || c.width > best.width || (c.width == best.width && c.density > best.density) {
+			best = c
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.url
+}
+
+// setAttr sets an attribute, adding it if missing
+func setAttr(n *html.Node, key, value string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
@@ -0,0 +1,53 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveImages(t *testing.T) {
+	content := `
+		<html>
+			<body>
+				<img src="data:image/gif;base64,R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7" data-src="/images/lazy.jpg" alt="Lazy">
+				<img src="/img/small.jpg" srcset="/img/small.jpg 480w, /img/large.jpg 1200w,/img/medium.jpg 800w" alt="Responsive">
+				<img data-srcset="/img/a.jpg 1x, /img/a@2x.jpg 2x" alt="Density">
+				<picture>
+					<source type="image/webp" srcset="/img/hero.webp 1600w">
+					<img src="/img/hero-fallback.jpg" alt="Hero">
+				</picture>
+				<img src="/assets/spacer.gif" alt="Spacer">
+				<img src="data:image/svg+xml,%3Csvg xmlns='http://www.w3.org/2000/svg'%3E%3C/svg%3E" alt="Placeholder">
+				<img src="/img/plain.png" alt="Plain">
+			</body>
+		</html>`
+
+	result := string(CleanHTML([]byte(content), DefaultCleaningOptions()))
+
+	for _, s := range []string{
+		`src="/images/lazy.jpg"`,
+		`src="/img/large.jpg"`,
+		`src="/img/a@2x.jpg"`,
+		`src="/img/hero.webp"`,
+		`src="/img/plain.png"`,
+	} {
+		if !strings.Contains(result, s) {
+			t.Errorf("Expected %s not found in result:\n%s", s, result)
+		}
+	}
+	for _, s := range []string{"data:image", "Spacer", "Placeholder", "data-srcset", "data-src", "480w"} {
+		if strings.Contains(result, s) {
+			t.Errorf("Unexpected %q found in result:\n%s", s, result)
+		}
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	candidates := parseSrcset("/a.jpg?w=100,200 1x, /b.jpg 2x,/c.jpg")
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %+v", candidates)
+	}
+	if candidates[0].url != "/a.jpg?w=100,200" || candidates[1].density != 2 || candidates[2].url != "/c.jpg" {
+		t.Errorf("unexpected candidates: %+v", candidates)
+	}
+}
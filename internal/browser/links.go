@@ -0,0 +1,202 @@
+package browser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Query parameters used only to track clicks and campaigns
+var trackingParams = map[string]bool{
+	"fbclid":      true,
+	"gclid":       true,
+	"gclsrc":      true,
+	"dclid":       true,
+	"gbraid":      true,
+	"wbraid":      true,
+	"msclkid":     true,
+	"yclid":       true,
+	"twclid":      true,
+	"ttclid":      true,
+	"igshid":      true,
+	"li_fat_id":   true,
+	"mc_cid":      true,
+	"mc_eid":      true,
+	"mkt_tok":     true,
+	"_hsenc":      true,
+	"_hsmi":       true,
+	"_gl":         true,
+	"oly_anon_id": true,
+	"oly_enc_id":  true,
+	"vero_id":     true,
+	"vero_conv":   true,
+	"ref_src":     true,
+	"ref_url":     true,
+}
+
+// Prefixes of tracking parameter families such as utm_source
+var trackingParamPrefixes = []string{"utm_", "pk_", "mtm_"}
+
+// redirector describes a link wrapper that sends the reader through a
+// redirect page, with the real target in a query parameter
+type redirector struct {
+	host  func(string) bool
+	path  string
+	param []string
+}
+
+var redirectors = []redirector{
+	{host: isGoogleHost, path: "/url", param: []string{"q", "url"}},
+	{host: hostIn("l.facebook.com", "lm.facebook.com", "l.messenger.com", "l.instagram.com"), path: "/l.php", param: []string{"u"}},
+	{host: hostSuffix(".safelinks.protection.outlook.com"), path: "/", param: []string{"url"}},
+	{host: hostIn("www.youtube.com", "youtube.com", "m.youtube.com"), path: "/redirect", param: []string{"q"}},
+	{host: hostIn("www.linkedin.com", "linkedin.com"), path: "/redir/redirect", param: []string{"url"}},
+	{host: hostIn("out.reddit.com"), path: "/", param: []string{"url"}},
+	{host: hostIn("duckduckgo.com"), path: "/l/", param: []string{"uddg"}},
+	{host: hostIn("vk.com", "m.vk.com"), path: "/away.php", param: []string{"to"}},
+}
+
+// maxUnwrap bounds how many nested redirect wrappers are removed
+const maxUnwrap = 5
+
+// normalizeLink unwraps redirector links into their real target and strips
+// tracking parameters. Values that are not absolute http(s) URLs are
+// returned unchanged, as is the original spelling of untouched URLs.
+func normalizeLink(href string) string {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return href
+	}
+
+	changed := false
+	for i := 0; i < maxUnwrap; i++ {
+		target := unwrapRedirect(u)
+		if target == nil {
+			break
+		}
+		u, changed = target, true
+	}
+
+	if query, stripped := stripTrackingParams(u.RawQuery); stripped {
+		u.RawQuery, changed = query, true
+	}
+
+	if !changed {
+		return href
+	}
+	return u.String()
+}
+
+// unwrapRedirect returns the target of a redirector link, or nil
+func unwrapRedirect(u *url.URL) *url.URL {
+	host := strings.ToLower(u.Hostname())
+	for _, r := range redirectors {
+		if !r.host(host) || u.Path != r.path {
+			continue
+		}
+		query := u.Query()
+		for _, param := range r.param {
+			target, err := url.Parse(query.Get(param))
+			if err == nil && target.Host != "" && (target.Scheme == "http" || target.Scheme == "https") {
+				return target
+			}
+		}
+	}
+	return nil
+}
+
+// stripTrackingParams removes tracking parameters from a raw query, keeping
+// the order and encoding of the others. It reports whether any were removed.
+func stripTrackingParams(rawQuery string) (string, bool) {
+	if rawQuery == "" {
+		return rawQuery, false
+	}
+	var kept []string
+	stripped := false
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && isTrackingParam(strings.ToLower(name)) {
+			stripped = true
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&"), stripped
+}
+
+func isTrackingParam(name string) bool {
+	if trackingParams[name] {
+		return true
+	}
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// googleDomains lists the top-level domains Google search is served on, as
+// in google.co.uk, from https://www.google.com/supported_domains
+var googleDomains = map[string]bool{
+	"com": true, "ad": true, "ae": true, "com.af": true, "com.ag": true,
+	"al": true, "am": true, "co.ao": true, "com.ar": true, "as": true,
+	"at": true, "com.au": true, "az": true, "ba": true, "com.bd": true,
+	"be": true, "bf": true, "bg": true, "com.bh": true, "bi": true, "bj": true,
+	"com.bn": true, "com.bo": true, "com.br": true, "bs": true, "bt": true,
+	"co.bw": true, "by": true, "com.bz": true, "ca": true, "cat": true,
+	"cd": true, "cf": true, "cg": true, "ch": true, "ci": true, "co.ck": true,
+	"cl": true, "cm": true, "cn": true, "com.co": true, "co.cr": true,
+	"com.cu": true, "cv": true, "com.cy": true, "cz": true, "de": true,
+	"dj": true, "dk": true, "dm": true, "com.do": true, "dz": true,
+	"com.ec": true, "ee": true, "com.eg": true, "es": true, "com.et": true,
+	"fi": true, "com.fj": true, "fm": true, "fr": true, "ga": true, "ge": true,
+	"gg": true, "com.gh": true, "com.gi": true, "gl": true, "gm": true,
+	"gr": true, "com.gt": true, "gy": true, "com.hk": true, "hn": true,
+	"hr": true, "ht": true, "hu": true, "co.id": true, "ie": true,
+	"co.il": true, "im": true, "co.in": true, "iq": true, "is": true,
+	"it": true, "je": true, "com.jm": true, "jo": true, "co.jp": true,
+	"co.ke": true, "com.kh": true, "ki": true, "kg": true, "co.kr": true,
+	"com.kw": true, "kz": true, "la": true, "com.lb": true, "li": true,
+	"lk": true, "co.ls": true, "lt": true, "lu": true, "lv": true,
+	"com.ly": true, "co.ma": true, "md": true, "me": true, "mg": true,
+	"mk": true, "ml": true, "com.mm": true, "mn": true, "com.mt": true,
+	"mu": true, "mv": true, "mw": true, "com.mx": true, "com.my": true,
+	"co.mz": true, "com.na": true, "com.ng": true, "com.ni": true, "ne": true,
+	"nl": true, "no": true, "com.np": true, "nr": true, "nu": true,
+	"co.nz": true, "com.om": true, "com.pa": true, "com.pe": true,
+	"com.pg": true, "com.ph": true, "com.pk": true, "pl": true, "pn": true,
+	"com.pr": true, "ps": true, "pt": true, "com.py": true, "com.qa": true,
+	"ro": true, "rs": true, "ru": true, "rw": true, "com.sa": true,
+	"com.sb": true, "sc": true, "se": true, "com.sg": true, "sh": true,
+	"si": true, "sk": true, "com.sl": true, "sn": true, "so": true, "sm": true,
+	"sr": true, "st": true, "com.sv": true, "td": true, "tg": true,
+	"co.th": true, "com.tj": true, "tl": true, "tm": true, "tn": true,
+	"to": true, "com.tr": true, "tt": true, "com.tw": true, "co.tz": true,
+	"com.ua": true, "co.ug": true, "co.uk": true, "com.uy": true, "co.uz": true,
+	"com.vc": true, "co.ve": true, "co.vi": true, "com.vn": true, "vu": true,
+	"ws": true, "co.za": true, "co.zm": true, "co.zw": true,
+}
+
+// isGoogleHost matches google.com and its country domains, e.g.
+// www.google.co.uk
+func isGoogleHost(host string) bool {
+	tld, ok := strings.CutPrefix(strings.TrimPrefix(host, "www."), "google.")
+	return ok && googleDomains[tld]
+}
+
+func hostIn(hosts ...string) func(string) bool {
+	return func(host string) bool {
+		for _, h := range hosts {
+			if host == h {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func hostSuffix(suffix string) func(string) bool {
+	return func(host string) bool {
+		return strings.HasSuffix(host, suffix)
+	}
+}
@@ -0,0 +1,46 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeLink(t *testing.T) {
+	tests := []struct {
+		href     string
+		expected string
+	}{
+		{"https://example.com/post?utm_source=news&utm_medium=email&id=42", "https://example.com/post?id=42"},
+		{"https://example.com/post?fbclid=abc123", "https://example.com/post"},
+		{"https://example.com/a?x=1&gclid=z&y=%20b#top", "https://example.com/a?x=1&y=%20b#top"},
+		{"https://www.google.com/url?q=https://example.com/page%3Futm_campaign%3Dx&sa=D", "https://example.com/page"},
+		{"https://www.google.co.uk/url?url=https%3A%2F%2Fexample.com%2F", "https://example.com/"},
+		{"https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2Fstory&h=AT0", "https://example.com/story"},
+		{"https://eur01.safelinks.protection.outlook.com/?url=https%3A%2F%2Fexample.com%2Fdoc&data=05", "https://example.com/doc"},
+		{"https://google.evil.com/url?q=https://example.com/", "https://google.evil.com/url?q=https://example.com/"},
+		{"https://www.google.com/url?q=not-a-url", "https://www.google.com/url?q=not-a-url"},
+		{"https://example.com/search?q=golang", "https://example.com/search?q=golang"},
+		{"/docs/intro?utm_source=x", "/docs/intro?utm_source=x"},
+		{"mailto:team@example.com", "mailto:team@example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeLink(tt.href); got != tt.expected {
+			t.Errorf("normalizeLink(%q) = %q, expected %q", tt.href, got, tt.expected)
+		}
+	}
+}
+
+func TestCleanHTMLTrackingParams(t *testing.T) {
+	content := `<html><body><a href="https://example.com/?utm_source=feed">Home</a></body></html>`
+
+	result := string(CleanHTML([]byte(content), DefaultCleaningOptions()))
+	if !strings.Contains(result, `href="https://example.com/"`) {
+		t.Errorf("Expected tracking parameters to be stripped:\n%s", result)
+	}
+
+	result = string(CleanHTML([]byte(content), &CleaningOptions{KeepTrackingParams: true}))
+	if !strings.Contains(result, `utm_source=feed`) {
+		t.Errorf("Expected tracking parameters to be kept:\n%s", result)
+	}
+}
@@ -0,0 +1,38 @@
+package browser
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// isTeXScript reports whether a script element holds the TeX source of a
+// formula, as MathJax 2 writes them
+func isTeXScript(n *html.Node) bool {
+	t, _, _ := strings.Cut(attr(n, "type"), ";")
+	return n.Data == "script" && strings.EqualFold(strings.TrimSpace(t), "math/tex")
+}
+
+// isMathOnly reports whether an element holds nothing but a MathML formula.
+// Pages such as Wikipedia hide the MathML and show an image of the formula,
+// which is not worth dropping the formula for.
+func isMathOnly(n *html.Node) bool {
+	if n.Data == "math" {
+		return true
+	}
+	var child *html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			if child != nil {
+				return false
+			}
+			child = c
+		case html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				return false
+			}
+		}
+	}
+	return child != nil && isMathOnly(child)
+}
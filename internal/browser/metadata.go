@@ -0,0 +1,71 @@
+package browser
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nathabonfim59/md-fetch/internal/metadata"
+	"golang.org/x/net/html"
+)
+
+// isJSONLD reports whether a script element holds JSON-LD structured data
+func isJSONLD(n *html.Node) bool {
+	t, _, _ := strings.Cut(attr(n, "type"), ";")
+	return n.Data == "script" && strings.EqualFold(strings.TrimSpace(t), "application/ld+json")
+}
+
+// hoistJSONLD moves JSON-LD scripts into the document head, where the page
+// metadata survives the selector and readability passes that reduce the body
+func hoistJSONLD(doc *html.Node) {
+	head := findElement(doc, "head")
+	body := findElement(doc, "body")
+	if head == nil || body == nil {
+		return
+	}
+
+	var scripts []*html.Node
+	walk(body, func(n *html.Node) {
+		if n.Type == html.ElementNode && isJSONLD(n) {
+			scripts = append(scripts, n)
+		}
+	})
+	for _, script := range scripts {
+		script.Parent.RemoveChild(script)
+		head.AppendChild(script)
+	}
+}
+
+// hoistStructuredData converts the microdata and RDFa items of a page into a
+// JSON-LD script in the head, so they survive the passes that reduce the
+// body as JSON-LD does. The itemscope and typeof attributes of the items are
+// dropped, so the items are not read twice, and the script is marked so the
+// page metadata is not taken from them.
+func hoistStructuredData(doc *html.Node, pageURL string) {
+	head := findElement(doc, "head")
+	if head == nil {
+		return
+	}
+
+	items := append(metadata.MicrodataItems(doc, pageURL), metadata.RDFaItems(doc, pageURL)...)
+	if len(items) == 0 {
+		return
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return
+	}
+
+	walk(doc, func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			removeAttr(n, "itemscope")
+			removeAttr(n, "typeof")
+		}
+	})
+	script := &html.Node{
+		Type: html.ElementNode,
+		Data: "script",
+		Attr: []html.Attribute{{Key: "type", Val: "application/ld+json"}, {Key: metadata.HoistedAttr}},
+	}
+	script.AppendChild(&html.Node{Type: html.TextNode, Data: string(data)})
+	head.AppendChild(script)
+}
@@ -0,0 +1,28 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathabonfim59/md-fetch/internal/metadata"
+)
+
+func TestHoistStructuredData(t *testing.T) {
+	html := `<html><head></head><body>
+		<div class="sidebar" itemscope itemtype="https://schema.org/Product">
+			<span itemprop="name">Widget</span>
+			<meta itemprop="sku" content="W-1">
+		</div>
+		<article><h1>Review</h1><p>Body</p></article>
+	</body></html>`
+
+	result := string(CleanHTMLWithURL([]byte(html), "https://example.com/review", &CleaningOptions{Select: "article"}))
+
+	script := `<script type="application/ld+json" ` + metadata.HoistedAttr + `="">[{"@context":"https://schema.org","@type":"Product","name":"Widget","sku":"W-1"}]</script>`
+	if !strings.Contains(result, script) {
+		t.Errorf("expected the item in a JSON-LD script, got:\n%s", result)
+	}
+	if strings.Contains(result, "Widget</span>") || strings.Contains(result, "itemscope") {
+		t.Errorf("expected the sidebar to be removed and no item left, got:\n%s", result)
+	}
+}
@@ -0,0 +1,352 @@
+package browser
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Readability-style main content extraction. Block elements are scored by the
+// amount of prose they contain, their link density and their semantic tags or
+// class names; the best scoring subtree is kept and everything around it
+// (sidebars, related-article lists, comment sections) is dropped.
+
+var (
+	unlikelyCandidatePattern = regexp.MustCompile(`(?i)-ad-|ad-break|advert|agegate|banner|breadcrumb|combx|comment|community|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|outbrain|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|taboola|tweet|twitter|widget`)
+	maybeCandidatePattern    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveClassPattern     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeClassPattern     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|widget`)
+)
+
+// Elements that are never part of the main content
+var readabilityStripTags = map[string]bool{
+	"aside":  true,
+	"form":   true,
+	"nav":    true,
+	"button": true,
+	"dialog": true,
+	"iframe": true,
+	"select": true,
+	"input":  true,
+}
+
+// Elements whose text contributes to the score of their ancestors
+var readabilityScoreTags = map[string]bool{
+	"p":          true,
+	"pre":        true,
+	"td":         true,
+	"blockquote": true,
+	"section":    true,
+	"h2":         true,
+	"h3":         true,
+	"li":         true,
+}
+
+// Minimum text length for an element to contribute to scoring
+const readabilityMinTextLength = 25
+
+type readabilityScorer struct {
+	scores map[*html.Node]float64
+}
+
+// extractMainContent reduces the document body to its main content. The
+// document is left untouched when no candidate stands out.
+func extractMainContent(doc *html.Node) {
+	body := findElement(doc, "body")
+	if body == nil {
+		return
+	}
+
+	removeNonContent(body)
+	removeUnlikelyCandidates(body)
+
+	s := &readabilityScorer{scores: make(map[*html.Node]float64)}
+	top := s.topCandidate(body)
+	if top == nil || top == body {
+		return
+	}
+
+	// The page title often sits outside the article container
+	title := findElement(body, "h1")
+	content := s.collectContent(top)
+	pruneClutter(content)
+	if title != nil && findElement(content, "h1") == nil {
+		title.Parent.RemoveChild(title)
+		content.InsertBefore(title, content.FirstChild)
+	}
+
+	for c := body.FirstChild; c != nil; {
+		next := c.NextSibling
+		body.RemoveChild(c)
+		c = next
+	}
+	body.AppendChild(content)
+}
+
+// removeNonContent drops elements whose text must not count towards scores.
+// TeX scripts are formulas and stay with the text around them.
+func removeNonContent(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && ((c.Data == "script" && !isTeXScript(c)) || c.Data == "style" || c.Data == "noscript" || c.Data == "template") {
+			n.RemoveChild(c)
+		} else {
+			removeNonContent(c)
+		}
+		c = next
+	}
+}
+
+// removeUnlikelyCandidates drops elements whose tag, class or id mark them as
+// page chrome rather than content
+func removeUnlikelyCandidates(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && isUnlikelyCandidate(c) {
+			n.RemoveChild(c)
+		} else {
+			removeUnlikelyCandidates(c)
+		}
+		c = next
+	}
+}
+
+func isUnlikelyCandidate(n *html.Node) bool {
+	if readabilityStripTags[n.Data] {
+		return true
+	}
+	switch n.Data {
+	case "body", "article", "main", "a", "table", "tbody", "tr", "td", "th", "pre", "code":
+		return false
+	}
+	if attr(n, "role") == "complementary" || attr(n, "role") == "navigation" {
+		return true
+	}
+
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidatePattern.MatchString(match) && !maybeCandidatePattern.MatchString(match)
+}
+
+// topCandidate scores every block element by the text it contains and returns
+// the highest scoring one
+func (s *readabilityScorer) topCandidate(body *html.Node) *html.Node {
+	var candidates []*html.Node
+
+	walk(body, func(n *html.Node) {
+		if n.Type != html.ElementNode || !readabilityScoreTags[n.Data] {
+			return
+		}
+		text := textContent(n)
+		if len(text) < readabilityMinTextLength {
+			return
+		}
+
+		score := 1.0
+		score += float64(strings.Count(text, ","))
+		score += min(float64(len(text))/100, 3)
+
+		// Propagate the score to ancestors, decreasing with distance
+		level := 0
+		for p := n.Parent; p != nil && p.Type == html.ElementNode && level < 3; p = p.Parent {
+			if _, ok := s.scores[p]; !ok {
+				s.scores[p] = initialScore(p)
+				candidates = append(candidates, p)
+			}
+			switch level {
+			case 0:
+				s.scores[p] += score
+			case 1:
+				s.scores[p] += score / 2
+			default:
+				s.scores[p] += score / float64(level*3)
+			}
+			level++
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, c := range candidates {
+		score := s.scores[c] * (1 - linkDensity(c))
+		s.scores[c] = score
+		if top == nil || score > topScore {
+			top, topScore = c, score
+		}
+	}
+
+	// A candidate that is the only child of its parent carries no more
+	// content than the parent, which may hold a title or byline
+	for top != nil && top.Parent != nil && top.Parent != body && onlyElementChild(top.Parent) == top {
+		top = top.Parent
+	}
+	return top
+}
+
+// collectContent returns the top candidate together with siblings that look
+// like part of the same article
+func (s *readabilityScorer) collectContent(top *html.Node) *html.Node {
+	threshold := max(10, s.scores[top]*0.2)
+	content := &html.Node{Type: html.ElementNode, Data: "div"}
+
+	parent := top.Parent
+	if parent == nil {
+		top.Parent = nil
+		content.AppendChild(top)
+		return content
+	}
+
+	for c := parent.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c == top || s.isRelatedSibling(c, threshold) {
+			parent.RemoveChild(c)
+			content.AppendChild(c)
+		}
+		c = next
+	}
+	return content
+}
+
+func (s *readabilityScorer) isRelatedSibling(n *html.Node, threshold float64) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if score, ok := s.scores[n]; ok && score >= threshold {
+		return true
+	}
+	if n.Data != "p" {
+		return false
+	}
+
+	text := textContent(n)
+	density := linkDensity(n)
+	if len(text) > 80 && density < 0.25 {
+		return true
+	}
+	return len(text) > 0 && len(text) <= 80 && density == 0 && strings.ContainsAny(text, ".!?")
+}
+
+// pruneClutter removes link lists and other low-content blocks left inside the
+// extracted content, such as "related articles" boxes
+func pruneClutter(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && isClutter(c) {
+			n.RemoveChild(c)
+		} else {
+			pruneClutter(c)
+		}
+		c = next
+	}
+}
+
+func isClutter(n *html.Node) bool {
+	switch n.Data {
+	case "div", "section", "ul", "ol", "table":
+	default:
+		return false
+	}
+	if hasDescendant(n, "pre") || hasDescendant(n, "img") && len(textContent(n)) < readabilityMinTextLength {
+		return false
+	}
+
+	weight := classWeight(n)
+	if weight < 0 {
+		return true
+	}
+
+	text := textContent(n)
+	return len(text) > 0 && linkDensity(n) > 0.5 && weight <= 0
+}
+
+// initialScore seeds a candidate score from its tag and class names
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.Data {
+	case "article", "main":
+		score += 25
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	if attr(n, "role") == "main" || attr(n, "itemprop") == "articleBody" {
+		score += 25
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeClassPattern.MatchString(value) {
+			weight -= 25
+		}
+		if positiveClassPattern.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity returns the share of an element's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	textLength := len(textContent(n))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	walk(n, func(c *html.Node) {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			linkLength += len(textContent(c))
+		}
+	})
+	return min(float64(linkLength)/float64(textLength), 1)
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func hasDescendant(n *html.Node, tag string) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if findElement(c, tag) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyElementChild returns the single element child of n, ignoring
+// whitespace, or nil if n has zero or several element children
+func onlyElementChild(n *html.Node) *html.Node {
+	var only *html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.ElementNode:
+			if only != nil {
+				return nil
+			}
+			only = c
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) != "":
+			return nil
+		}
+	}
+	return only
+}
@@ -0,0 +1,77 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadability(t *testing.T) {
+	input := `<html>
+		<head><title>The Big Story</title></head>
+		<body>
+			<div class="topbar"><a href="/">Home</a> <a href="/about">About</a></div>
+			<div class="wrapper">
+				<div class="col-left">
+					<h1>The Big Story</h1>
+					<div class="post-body">
+						<p>First paragraph of the article, with commas, clauses, and enough text to be scored.</p>
+						<p>Second paragraph, also long enough to be counted, describing the events of the day.</p>
+						<pre><code>x := 1</code></pre>
+						<div class="related-posts">
+							<ul>
+								<li><a href="/1">Another story title that is fairly long</a></li>
+								<li><a href="/2">Yet another story title you might like</a></li>
+							</ul>
+						</div>
+					</div>
+					<div id="comments">
+						<p>Great article, thanks for writing it, I really enjoyed reading it.</p>
+					</div>
+				</div>
+				<div class="col-right">
+					<ul>
+						<li><a href="/x">Popular: something very interesting to click</a></li>
+						<li><a href="/y">Popular: another thing you want to click on</a></li>
+					</ul>
+				</div>
+			</div>
+			<div class="site-foot"><p>Copyright 2024, all rights reserved, Example Corp.</p></div>
+		</body>
+	</html>`
+
+	result := string(CleanHTML([]byte(input), &CleaningOptions{Readability: true}))
+
+	contains := []string{
+		"<h1>The Big Story</h1>",
+		"First paragraph of the article",
+		"Second paragraph",
+		"<pre><code>x := 1</code></pre>",
+	}
+	for _, s := range contains {
+		if !strings.Contains(result, s) {
+			t.Errorf("Expected content %q not found in result:\n%s", s, result)
+		}
+	}
+
+	excludes := []string{
+		"About",
+		"Another story title",
+		"Great article",
+		"Popular:",
+		"Copyright",
+	}
+	for _, s := range excludes {
+		if strings.Contains(result, s) {
+			t.Errorf("Unexpected content %q found in result:\n%s", s, result)
+		}
+	}
+}
+
+func TestReadabilityWithoutCandidate(t *testing.T) {
+	input := `<html><body><p>Short.</p></body></html>`
+
+	result := string(CleanHTML([]byte(input), &CleaningOptions{Readability: true}))
+	if !strings.Contains(result, "<p>Short.</p>") {
+		t.Errorf("Expected document to be left untouched, got:\n%s", result)
+	}
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Validate reports whether the CSS selectors in the options can be parsed
+func (o *CleaningOptions) Validate() error {
+	if o.Select != "" {
+		if _, err := cascadia.Compile(o.Select); err != nil {
+			return fmt.Errorf("invalid select selector %q: %v", o.Select, err)
+		}
+	}
+	if o.Remove != "" {
+		if _, err := cascadia.Compile(o.Remove); err != nil {
+			return fmt.Errorf("invalid remove selector %q: %v", o.Remove, err)
+		}
+	}
+	return nil
+}
+
+// applySelectors drops elements matching opts.Remove and then reduces the
+// body to the subtrees matching opts.Select. Invalid selectors are ignored,
+// callers are expected to check them with Validate.
+func applySelectors(doc *html.Node, opts *CleaningOptions) {
+	if opts.Remove != "" {
+		if sel, err := cascadia.Compile(opts.Remove); err == nil {
+			removeMatching(doc, sel)
+		}
+	}
+	if opts.Select != "" {
+		if sel, err := cascadia.Compile(opts.Select); err == nil {
+			keepMatching(doc, sel)
+		}
+	}
+}
+
+func removeMatching(n *html.Node, sel cascadia.Selector) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && sel.Match(c) {
+			n.RemoveChild(c)
+		} else {
+			removeMatching(c, sel)
+		}
+		c = next
+	}
+}
+
+// keepMatching replaces the body content with the outermost elements matching
+// the selector, in document order. The document is left untouched when
+// nothing matches.
+func keepMatching(doc *html.Node, sel cascadia.Selector) {
+	body := findElement(doc, "body")
+	if body == nil {
+		return
+	}
+
+	var matches []*html.Node
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if sel.Match(c) {
+				matches = append(matches, c)
+				continue
+			}
+			collect(c)
+		}
+	}
+	collect(body)
+
+	if len(matches) == 0 {
+		return
+	}
+
+	for _, m := range matches {
+		m.Parent.RemoveChild(m)
+	}
+	for c := body.FirstChild; c != nil; {
+		next := c.NextSibling
+		body.RemoveChild(c)
+		c = next
+	}
+	for _, m := range matches {
+		body.AppendChild(m)
+	}
+}
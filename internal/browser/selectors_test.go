@@ -0,0 +1,82 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectors(t *testing.T) {
+	html := `
+		<html>
+			<body>
+				<div class="sidebar">Sidebar links</div>
+				<article class="main">
+					<h1>Article Title</h1>
+					<p>Article body</p>
+					<div class="share-bar">Share this</div>
+					<div class="ads">Buy now</div>
+				</article>
+				<article class="teaser">Teaser text</article>
+			</body>
+		</html>`
+
+	tests := []struct {
+		name     string
+		opts     *CleaningOptions
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "select",
+			opts:     &CleaningOptions{Select: "article.main"},
+			contains: []string{"Article Title", "Article body", "Share this"},
+			excludes: []string{"Sidebar links", "Teaser text"},
+		},
+		{
+			name:     "remove",
+			opts:     &CleaningOptions{Remove: ".ads, .share-bar"},
+			contains: []string{"Sidebar links", "Article body", "Teaser text"},
+			excludes: []string{"Share this", "Buy now"},
+		},
+		{
+			name:     "select and remove",
+			opts:     &CleaningOptions{Select: "article", Remove: ".ads, .share-bar"},
+			contains: []string{"Article body", "Teaser text"},
+			excludes: []string{"Sidebar links", "Share this", "Buy now"},
+		},
+		{
+			name:     "select without matches",
+			opts:     &CleaningOptions{Select: "#missing"},
+			contains: []string{"Sidebar links", "Article body"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(CleanHTML([]byte(html), tt.opts))
+
+			for _, s := range tt.contains {
+				if !strings.Contains(result, s) {
+					t.Errorf("Expected content %q not found in result:\n%s", s, result)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(result, s) {
+					t.Errorf("Unexpected content %q found in result:\n%s", s, result)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSelectors(t *testing.T) {
+	if err := (&CleaningOptions{Select: "article.main", Remove: ".ads, .share-bar"}).Validate(); err != nil {
+		t.Errorf("expected valid selectors, got %v", err)
+	}
+	if err := (&CleaningOptions{Select: "article["}).Validate(); err == nil {
+		t.Error("expected error for invalid select selector")
+	}
+	if err := (&CleaningOptions{Remove: "..ads"}).Validate(); err == nil {
+		t.Error("expected error for invalid remove selector")
+	}
+}
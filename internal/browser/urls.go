@@ -0,0 +1,90 @@
+package browser
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attributes holding a single URL
+var urlAttrs = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"poster": true,
+	"cite":   true,
+}
+
+// absolutizeURLs rewrites relative link and media URLs against the page URL,
+// or the document's <base href> when there is one. Links to fragments of the
+// same page are left alone.
+func absolutizeURLs(doc *html.Node, pageURL string) {
+	base, err := url.Parse(pageURL)
+	if err != nil || !base.IsAbs() {
+		return
+	}
+	if href, ok := baseHref(doc); ok {
+		if ref, err := url.Parse(href); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+
+	walk(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode || n.Data == "base" {
+			return
+		}
+		for i := range n.Attr {
+			a := &n.Attr[i]
+			switch {
+			case urlAttrs[a.Key]:
+				a.Val = resolveURL(base, a.Val)
+			case a.Key == "srcset":
+				a.Val = resolveSrcset(base, a.Val)
+			}
+		}
+	})
+}
+
+// baseHref returns the href of the first <base> element
+func baseHref(doc *html.Node) (string, bool) {
+	var href string
+	var found bool
+	walk(doc, func(n *html.Node) {
+		if !found && n.Type == html.ElementNode && n.Data == "base" {
+			href, found = attr(n, "href"), hasAttr(n, "href")
+		}
+	})
+	return strings.TrimSpace(href), found
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveURL resolves a relative reference, returning other values unchanged
+func resolveURL(base *url.URL, value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return value
+	}
+	ref, err := url.Parse(trimmed)
+	if err != nil || ref.Scheme != "" {
+		return value
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// resolveSrcset resolves every candidate URL of a srcset attribute
+func resolveSrcset(base *url.URL, srcset string) string {
+	candidates := parseSrcset(srcset)
+	parts := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		parts = append(parts, strings.TrimSpace(resolveURL(base, c.url)+" "+c.descriptors))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,58 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAbsoluteURLs(t *testing.T) {
+	content := `
+		<html>
+			<body>
+				<a href="/docs/intro">Intro</a>
+				<a href="guide.html?page=2">Guide</a>
+				<a href="#install">Install</a>
+				<a href="mailto:team@example.com">Mail</a>
+				<a href="https://other.example.org/page">Other</a>
+				<img src="../images/logo.png" alt="Logo">
+				<picture><source srcset="/img/a.webp 1x, /img/b.webp 2x"><img src="/img/a.jpg" alt="Hero"></picture>
+			</body>
+		</html>`
+
+	result := string(CleanHTMLWithURL([]byte(content), "https://example.com/blog/post/", DefaultCleaningOptions()))
+	for _, s := range []string{
+		`href="https://example.com/docs/intro"`,
+		`href="https://example.com/blog/post/guide.html?page=2"`,
+		`href="#install"`,
+		`href="mailto:team@example.com"`,
+		`href="https://other.example.org/page"`,
+		`src="https://example.com/blog/images/logo.png"`,
+		`srcset="https://example.com/img/a.webp 1x, https://example.com/img/b.webp 2x"`,
+	} {
+		if !strings.Contains(result, s) {
+			t.Errorf("Expected %s not found in result:\n%s", s, result)
+		}
+	}
+
+	withBase := `<html><head><base href="/v2/"></head><body><a href="intro">Intro</a></body></html>`
+	result = string(CleanHTMLWithURL([]byte(withBase), "https://example.com/docs/", DefaultCleaningOptions()))
+	if !strings.Contains(result, `href="https://example.com/v2/intro"`) {
+		t.Errorf("Expected link resolved against <base href>:\n%s", result)
+	}
+
+	result = string(CleanHTMLWithURL([]byte(content), "https://example.com/blog/post/", &CleaningOptions{KeepRelativeURLs: true}))
+	if !strings.Contains(result, `href="/docs/intro"`) {
+		t.Errorf("Expected relative link to be kept:\n%s", result)
+	}
+}
+
+func TestAbsoluteURLsIgnoreCanonical(t *testing.T) {
+	// The canonical URL names the preferred copy of a page, not the address
+	// it was served from, so relative URLs are not resolved against it
+	content := `<html><head><link rel="canonical" href="https://amp.example.org/article"><meta property="og:url" content="https://cdn.example.net/a/"></head>
+		<body><img src="img.png" alt="Chart"></body></html>`
+	result := string(CleanHTMLWithURL([]byte(content), "https://example.com/blog/post", DefaultCleaningOptions()))
+	if !strings.Contains(result, `src="https://example.com/blog/img.png"`) {
+		t.Errorf("expected the image resolved against the page URL:\n%s", result)
+	}
+}
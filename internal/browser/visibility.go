@@ -0,0 +1,130 @@
+package browser
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// hiddenAttr marks elements a rendering backend found invisible through their
+// computed style
+const hiddenAttr = "data-md-fetch-hidden"
+
+// Utility classes that hide content from sighted readers
+var screenReaderOnlyClasses = map[string]bool{
+	"sr-only":            true,
+	"sr-only-focusable":  true,
+	"screen-reader-text": true,
+	"screen-reader-only": true,
+	"visually-hidden":    true,
+	"visuallyhidden":     true,
+	"u-visually-hidden":  true,
+	"hidden-visually":    true,
+	"a11y-hidden":        true,
+	"element-invisible":  true,
+	"offscreen":          true,
+}
+
+// removeHidden drops every element that is not visible to readers
+func removeHidden(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && isHidden(c) && !isMathOnly(c) {
+			n.RemoveChild(c)
+		} else {
+			removeHidden(c)
+		}
+		c = next
+	}
+}
+
+// isHidden reports whether an element is not visible to readers, judging by
+// its markup and the marker left by rendering backends
+func isHidden(n *html.Node) bool {
+	switch n.Data {
+	case "template":
+		return true
+	case "input":
+		if strings.EqualFold(attr(n, "type"), "hidden") {
+			return true
+		}
+	}
+
+	for _, a := range n.Attr {
+		switch a.Key {
+		case hiddenAttr:
+			return true
+		case "hidden":
+			// hidden="until-found" content is revealed by find-in-page
+			if !strings.EqualFold(a.Val, "until-found") {
+				return true
+			}
+		case "aria-hidden":
+			if strings.EqualFold(strings.TrimSpace(a.Val), "true") {
+				return true
+			}
+		case "style":
+			if hiddenByStyle(a.Val) {
+				return true
+			}
+		case "class":
+			for _, class := range strings.Fields(a.Val) {
+				if screenReaderOnlyClasses[strings.ToLower(class)] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hiddenByStyle reports whether an inline style hides the element
+func hiddenByStyle(style string) bool {
+	declarations := parseStyle(style)
+	switch declarations["display"] {
+	case "none":
+		return true
+	}
+	switch declarations["visibility"] {
+	case "hidden", "collapse":
+		return true
+	}
+	return false
+}
+
+// isTrackingPixel reports whether an image is sized to at most one pixel
+func isTrackingPixel(n *html.Node) bool {
+	width, height := attr(n, "width"), attr(n, "height")
+	declarations := parseStyle(attr(n, "style"))
+	if w, ok := declarations["width"]; ok {
+		width = w
+	}
+	if h, ok := declarations["height"]; ok {
+		height = h
+	}
+	return isTinyLength(width) && isTinyLength(height)
+}
+
+func isTinyLength(value string) bool {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	if value == "" {
+		return false
+	}
+	size, err := strconv.ParseFloat(value, 64)
+	return err == nil && size <= 1
+}
+
+// parseStyle splits an inline style attribute into lower-cased declarations
+func parseStyle(style string) map[string]string {
+	declarations := make(map[string]string)
+	for _, decl := range strings.Split(style, ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		declarations[strings.ToLower(strings.TrimSpace(name))] = strings.ToLower(value)
+	}
+	return declarations
+}
@@ -0,0 +1,67 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemoveHidden(t *testing.T) {
+	html := `
+		<html>
+			<body>
+				<p>Visible text</p>
+				<p hidden>Hidden attribute</p>
+				<div hidden="until-found">Found on search</div>
+				<div aria-hidden="true">Decorative icon</div>
+				<div style="color: red; display: none !important">Inline display none</div>
+				<div style="visibility:hidden">Inline visibility hidden</div>
+				<span class="btn sr-only">Screen reader label</span>
+				<template><p>Template content</p></template>
+				<input type="hidden" name="csrf" value="secret-token">
+				<img src="/pixel.gif" width="1" height="1" alt="tracking pixel">
+				<img src="/photo.jpg" width="640" height="480" alt="Photo">
+				<div data-md-fetch-hidden="">Hidden by stylesheet</div>
+				<span style="display: none"><math alttext="x^2"><mi>hidden-formula</mi></math></span>
+			</body>
+		</html>`
+
+	hidden := []string{
+		"Hidden attribute",
+		"Decorative icon",
+		"Inline display none",
+		"Inline visibility hidden",
+		"Screen reader label",
+		"Template content",
+		"secret-token",
+		"tracking pixel",
+		"Hidden by stylesheet",
+	}
+
+	result := string(CleanHTML([]byte(html), DefaultCleaningOptions()))
+	// Hidden MathML is kept, as pages show images of the formula instead
+	for _, s := range []string{"Visible text", "Found on search", "Photo", "hidden-formula"} {
+		if !strings.Contains(result, s) {
+			t.Errorf("Expected content %q not found in result:\n%s", s, result)
+		}
+	}
+	for _, s := range hidden {
+		if strings.Contains(result, s) {
+			t.Errorf("Hidden content %q found in result:\n%s", s, result)
+		}
+	}
+
+	result = string(CleanHTML([]byte(html), &CleaningOptions{KeepHidden: true}))
+	for _, s := range hidden {
+		// Templates and hidden inputs are never rendered, tracking pixels are
+		// dropped with the other placeholder images
+		if s == "Template content" || s == "secret-token" || s == "tracking pixel" {
+			continue
+		}
+		if !strings.Contains(result, s) {
+			t.Errorf("Expected content %q to be kept with KeepHidden:\n%s", s, result)
+		}
+	}
+	if strings.Contains(result, hiddenAttr) {
+		t.Errorf("Expected visibility marker to be stripped:\n%s", result)
+	}
+}
@@ -0,0 +1,241 @@
+// Package chunks splits converted Markdown into size-bounded chunks along
+// its heading hierarchy, for retrieval pipelines.
+package chunks
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nathabonfim59/md-fetch/internal/tokens"
+)
+
+// Default chunk size and overlap, in tokens
+const (
+	DefaultSize    = 512
+	DefaultOverlap = 64
+)
+
+// Chunk is a part of a page. Start and End are character offsets of
+// Content in the page's Markdown.
+type Chunk struct {
+	URL         string   `json:"url"`
+	Index       int      `json:"index"`
+	HeadingPath []string `json:"heading_path"`
+	Content     string   `json:"content"`
+	Start       int      `json:"start"`
+	End         int      `json:"end"`
+	Tokens      int      `json:"tokens"`
+}
+
+// unit is a range of the text chunks are built from: a block, or a line,
+// sentence or word of a block too large for a chunk
+type unit struct {
+	start, end int // byte offsets
+	tokens     int
+}
+
+// section is the text under a heading, down to the next heading
+type section struct {
+	path  []string
+	units []unit
+}
+
+// Lines opening blocks whose blank lines do not end them
+var fences = []string{"```", "~~~"}
+
+var heading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+
+var (
+	lineEnd     = regexp.MustCompile(`\n`)
+	sentenceEnd = regexp.MustCompile(`[.!?]["')\]]*\s+`)
+	wordEnd     = regexp.MustCompile(`\s+`)
+)
+
+// Split splits Markdown into chunks of at most size tokens, each within a
+// single section. A chunk ends at a block boundary, or at a line, sentence
+// or word when a block alone is too large, and repeats up to overlap tokens
+// of whole blocks from the previous chunk of the same section. A size of 0
+// uses DefaultSize.
+func Split(markdown, url string, size, overlap int, encoding tokens.Encoding) []Chunk {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	chunks := []Chunk{}
+	for _, s := range splitSections(markdown) {
+		units := s.fit(markdown, size, encoding)
+		first := 0
+		for first < len(units) {
+			last, used := first, units[first].tokens
+			for last+1 < len(units) && used+1+units[last+1].tokens <= size {
+				last++
+				used += 1 + units[last].tokens
+			}
+
+			start, end := units[first].start, units[last].end
+			chunks = append(chunks, Chunk{
+				URL:         url,
+				Index:       len(chunks),
+				HeadingPath: s.path,
+				Content:     markdown[start:end],
+				Start:       utf8.RuneCountInString(markdown[:start]),
+				End:         utf8.RuneCountInString(markdown[:end]),
+				Tokens:      tokens.Count(markdown[start:end], encoding),
+			})
+			if last+1 >= len(units) {
+				break
+			}
+
+			// The next chunk starts with the trailing units of this one that
+			// fit the overlap, always moving forward
+			next, repeated := last+1, 0
+			for next-1 > first && repeated+units[next-1].tokens <= overlap {
+				next--
+				repeated += units[next].tokens
+			}
+			first = next
+		}
+	}
+	return chunks
+}
+
+// splitSections splits Markdown into sections at its headings, skipping
+// fenced code. A heading directly followed by a subheading is kept with
+// the subsection, so no chunk holds a lone heading.
+func splitSections(markdown string) []*section {
+	var sections []*section
+	var path []string
+	var levels []int
+	current := &section{path: []string{}}
+	blockStart := -1
+	fence := ""
+
+	endBlock := func(end int) {
+		if blockStart < 0 {
+			return
+		}
+		for end > blockStart && isSpace(markdown[end-1]) {
+			end--
+		}
+		if end > blockStart {
+			current.units = append(current.units, unit{start: blockStart, end: end})
+		}
+		blockStart = -1
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		lineStart, lineStop := offset, offset+len(strings.TrimRight(line, "\r\n"))
+		offset += len(line)
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		for _, f := range fences {
+			if strings.HasPrefix(trimmed, f) {
+				fence = f
+			}
+		}
+
+		if m := heading.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil && fence == "" {
+			endBlock(lineStart)
+			level := len(m[1])
+			subheading := len(levels) > 0 && level > levels[len(levels)-1]
+			for len(levels) > 0 && levels[len(levels)-1] >= level {
+				levels, path = levels[:len(levels)-1], path[:len(path)-1]
+			}
+			levels, path = append(levels, level), append(path, strings.TrimSpace(m[2]))
+
+			if len(current.units) > 0 && !(subheading && current.headingOnly()) {
+				sections = append(sections, current)
+				current = &section{}
+			}
+			current.path = append([]string{}, path...)
+			current.units = append(current.units, unit{start: lineStart, end: lineStop, tokens: -1})
+			continue
+		}
+
+		switch {
+		case trimmed == "" && fence == "":
+			endBlock(lineStart)
+		case blockStart < 0:
+			blockStart = lineStart
+		}
+	}
+	endBlock(len(markdown))
+	if len(current.units) > 0 {
+		sections = append(sections, current)
+	}
+	return sections
+}
+
+// headingOnly reports whether a section holds nothing but headings
+func (s *section) headingOnly() bool {
+	for _, u := range s.units {
+		if u.tokens != -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// fit counts the tokens of the section's units, splitting units larger
+// than size into lines, then sentences, then words
+func (s *section) fit(markdown string, size int, encoding tokens.Encoding) []unit {
+	var units []unit
+	for _, u := range s.units {
+		if u.start >= u.end {
+			continue
+		}
+		units = append(units, split(markdown, u, size, encoding, 0)...)
+	}
+	return units
+}
+
+// Separators tried in turn to split a unit that is too large
+var splitters = []*regexp.Regexp{lineEnd, sentenceEnd, wordEnd}
+
+// split counts the tokens of a unit, splitting it with the separator at
+// depth and beyond while it is larger than size
+func split(markdown string, u unit, size int, encoding tokens.Encoding, depth int) []unit {
+	text := markdown[u.start:u.end]
+	u.tokens = tokens.Count(text, encoding)
+	if u.tokens <= size || depth >= len(splitters) {
+		return []unit{u}
+	}
+
+	var units []unit
+	start := u.start
+	for _, cut := range append(indexesAfter(text, splitters[depth]), len(text)) {
+		part := unit{start: start, end: u.start + cut}
+		// Leave the separator out of the part
+		for part.end > part.start && isSpace(markdown[part.end-1]) {
+			part.end--
+		}
+		if part.end > part.start {
+			units = append(units, split(markdown, part, size, encoding, depth+1)...)
+		}
+		start = u.start + cut
+	}
+	return units
+}
+
+// indexesAfter returns the offsets just past each match of re in text
+func indexesAfter(text string, re *regexp.Regexp) []int {
+	var cuts []int
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		if m[1] < len(text) {
+			cuts = append(cuts, m[1])
+		}
+	}
+	return cuts
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
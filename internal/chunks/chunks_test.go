@@ -0,0 +1,90 @@
+package chunks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/nathabonfim59/md-fetch/internal/tokens"
+)
+
+func TestSplitHeadingPaths(t *testing.T) {
+	markdown := "Intro text.\n\n# Guide\n\n## Install\n\nRun the installer.\n\n### Linux\n\nUse the package.\n\n```sh\n# not a heading\n\napt install md-fetch\n```\n\n## Usage\n\nFetch a page."
+
+	chunks := Split(markdown, "https://example.com", DefaultSize, DefaultOverlap, tokens.CL100K)
+
+	expected := [][]string{
+		{},
+		{"Guide", "Install"},
+		{"Guide", "Install", "Linux"},
+		{"Guide", "Usage"},
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("expected %d chunks, got %d: %+v", len(expected), len(chunks), chunks)
+	}
+	for i, chunk := range chunks {
+		if !reflect.DeepEqual(chunk.HeadingPath, expected[i]) {
+			t.Errorf("chunk %d: expected heading path %v, got %v", i, expected[i], chunk.HeadingPath)
+		}
+		if chunk.Index != i || chunk.URL != "https://example.com" {
+			t.Errorf("chunk %d: unexpected index %d or URL %q", i, chunk.Index, chunk.URL)
+		}
+	}
+
+	// A heading directly followed by a subheading stays with it
+	if !strings.HasPrefix(chunks[1].Content, "# Guide\n\n## Install") {
+		t.Errorf("expected the lone heading in the subsection chunk, got %q", chunks[1].Content)
+	}
+	if !strings.Contains(chunks[2].Content, "apt install md-fetch\n```") {
+		t.Errorf("expected the code block to stay whole, got %q", chunks[2].Content)
+	}
+}
+
+func TestSplitSizeAndOverlap(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 20; i++ {
+		paragraphs = append(paragraphs, "Ünïcode paragraph with a handful of words in it.")
+	}
+	markdown := "# Title\n\n" + strings.Join(paragraphs, "\n\n")
+	size := 60
+
+	chunks := Split(markdown, "", size, 15, tokens.CL100K)
+	if len(chunks) < 3 {
+		t.Fatalf("expected the section to be split, got %d chunks", len(chunks))
+	}
+
+	runes := []rune(markdown)
+	for i, chunk := range chunks {
+		if chunk.Tokens > size {
+			t.Errorf("chunk %d: %d tokens exceed the size %d", i, chunk.Tokens, size)
+		}
+		if got := string(runes[chunk.Start:chunk.End]); got != chunk.Content {
+			t.Errorf("chunk %d: offsets %d-%d do not match its content", i, chunk.Start, chunk.End)
+		}
+		if i > 0 && chunk.Start >= chunks[i-1].End {
+			t.Errorf("chunk %d: expected an overlap with the previous chunk", i)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.End != utf8.RuneCountInString(markdown) {
+		t.Errorf("expected the last chunk to end the text, got %d", last.End)
+	}
+}
+
+func TestSplitLargeBlock(t *testing.T) {
+	markdown := "## Long\n\n" + strings.Repeat("One sentence of a long paragraph. ", 40)
+	size := 50
+
+	chunks := Split(markdown, "", size, 0, tokens.CL100K)
+	if len(chunks) < 2 {
+		t.Fatalf("expected the paragraph to be split, got %d chunks", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Tokens > size {
+			t.Errorf("chunk %d: %d tokens exceed the size %d", i, chunk.Tokens, size)
+		}
+		if !strings.HasSuffix(chunk.Content, ".") {
+			t.Errorf("chunk %d: expected a cut at a sentence, got %q", i, chunk.Content)
+		}
+	}
+}
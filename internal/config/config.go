@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nathabonfim59/md-fetch/internal/fetcher"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the settings read from the configuration file. Every
+// fetcher.Options field is available as a top-level key.
+type Config struct {
+	Browser         string `yaml:"browser"`
+	fetcher.Options `yaml:",inline"`
+}
+
+// Default returns the configuration used when no file is present
+func Default() *Config {
+	cfg := &Config{
+		Options: *fetcher.DefaultOptions(),
+	}
+	cfg.RulesDir = DefaultRulesDir()
+	return cfg
+}
+
+// DefaultPath returns the location of the user configuration file,
+// e.g. ~/.config/md-fetch/config.yaml on Linux
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "md-fetch", "config.yaml")
+}
+
+// DefaultRulesDir returns the directory site rules are read from by default,
+// e.g. ~/.config/md-fetch/rules on Linux
+func DefaultRulesDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "md-fetch", "rules")
+}
+
+// Load reads the configuration file at path. An empty path loads the file at
+// DefaultPath, which may be missing; an explicit path must exist.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+		if path == "" {
+			return cfg, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	cfg.RulesDir = expandHome(cfg.RulesDir)
+
+	return cfg, nil
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := `browser: firefox
+keep_header: true
+keep_comments: true
+readability: true
+remove: ".ads, .share-bar"
+rules_dir: ~/md-fetch-rules
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if cfg.Browser != "firefox" {
+		t.Errorf("expected browser firefox, got %q", cfg.Browser)
+	}
+	if !cfg.KeepHeader || !cfg.KeepComments || !cfg.Readability {
+		t.Errorf("expected keep_header, keep_comments and readability to be set, got %+v", cfg.Options)
+	}
+	if cfg.KeepFooter || cfg.KeepNav || cfg.KeepStyles {
+		t.Errorf("expected unset options to keep their defaults, got %+v", cfg.Options)
+	}
+	if cfg.Remove != ".ads, .share-bar" {
+		t.Errorf("expected remove selector, got %q", cfg.Remove)
+	}
+	if home, err := os.UserHomeDir(); err == nil && cfg.RulesDir != filepath.Join(home, "md-fetch-rules") {
+		t.Errorf("expected rules_dir in home directory, got %q", cfg.RulesDir)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown key", content: "keep_headers: true\n"},
+		{name: "invalid selector", content: "select: \"article[\"\n"},
+		{name: "invalid yaml", content: "keep_header: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("expected error loading config")
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected error for missing explicit config file")
+	}
+}
+
+func TestLoadDefaultPathMissing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("expected missing default config to be ignored, got %v", err)
+	}
+	if cfg.Browser != "" || cfg.KeepHeader {
+		t.Errorf("expected default config, got %+v", cfg)
+	}
+}
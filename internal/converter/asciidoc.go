@@ -0,0 +1,226 @@
+package converter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// ConvertToAsciiDoc converts HTML content to AsciiDoc. Links are written
+// inline unless opts.Links drops them, images follow opts.Images, with
+// downloads linking the remote images, and headings follow
+// opts.NormalizeHeadings.
+func ConvertToAsciiDoc(content []byte, opts *Options) string {
+	w := &asciiDocWriter{opts: opts}
+	blocks := ExtractBlocks(content)
+	if opts.NormalizeHeadings {
+		NormalizeHeadings(blocks)
+	}
+	return strings.TrimSpace(w.blocks(blocks, 1))
+}
+
+type asciiDocWriter struct {
+	opts *Options
+}
+
+func (w *asciiDocWriter) blocks(blocks []*Block, depth int) string {
+	var parts []string
+	for _, block := range blocks {
+		if text := w.block(block, depth); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// block writes a block, depth being the nesting level of lists
+func (w *asciiDocWriter) block(block *Block, depth int) string {
+	switch block.Type {
+	case BlockHeading:
+		// A single = is the document title, so sections start at ==
+		return strings.Repeat("=", min(block.Level+1, 6)) + " " + strings.ReplaceAll(w.inline(block), " +\n", " ")
+	case BlockParagraph:
+		return w.inline(block)
+	case BlockList:
+		marker := "*"
+		if block.Ordered {
+			marker = "."
+		}
+		marker = strings.Repeat(marker, depth)
+		items := make([]string, len(block.Children))
+		for i, item := range block.Children {
+			text := marker + " " + w.inline(item)
+			for _, child := range item.Children {
+				if child.Type == BlockList {
+					text += "\n" + w.block(child, depth+1)
+				} else {
+					// Other blocks are attached to the item by a list continuation
+					text += "\n+\n" + w.block(child, depth)
+				}
+			}
+			items[i] = text
+		}
+		return strings.Join(items, "\n")
+	case BlockCode:
+		header := ""
+		if block.Language != "" {
+			header = "[source," + block.Language + "]\n"
+		}
+		return header + "----\n" + block.Text + "\n----"
+	case BlockQuote:
+		return "____\n" + w.blocks(block.Children, 1) + "\n____"
+	case BlockTable:
+		var b strings.Builder
+		if block.Text != "" {
+			b.WriteString("." + block.Text + "\n")
+		}
+		if block.Header {
+			b.WriteString("[%header]\n")
+		}
+		b.WriteString("|===\n")
+		for _, row := range block.Rows {
+			for i, cell := range row {
+				if i > 0 {
+					b.WriteString(" ")
+				}
+				b.WriteString("| " + strings.ReplaceAll(cell, "|", `\|`))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("|===")
+		return b.String()
+	case BlockImage:
+		switch w.opts.Images {
+		case ImageAlt:
+			return block.Alt
+		case ImageDrop:
+			return ""
+		}
+		return "image::" + block.Src + "[" + asciiDocAttr(block.Alt) + "]"
+	case BlockMath:
+		return "[latexmath]\n++++\n" + block.Text + "\n++++"
+	case BlockRule:
+		return "'''"
+	case BlockPage:
+		return "// Page " + strconv.Itoa(block.Page)
+	}
+	return block.Text
+}
+
+// inline writes the inline content of a block with its formatting
+func (w *asciiDocWriter) inline(block *Block) string {
+	if block.inline == nil {
+		return block.Text
+	}
+	var b strings.Builder
+	for _, n := range block.inline {
+		w.node(&b, n)
+	}
+	lines := strings.Split(b.String(), "\n")
+	var kept []string
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, " +\n")
+}
+
+func (w *asciiDocWriter) node(b *strings.Builder, n *html.Node) {
+	switch {
+	case n.Type == html.TextNode:
+		b.WriteString(strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return ' '
+			}
+			return r
+		}, n.Data))
+		return
+	case n.Type != html.ElementNode || skippedTags[n.Data]:
+		return
+	}
+
+	switch n.Data {
+	case "br":
+		b.WriteString("\n")
+	case "strong", "b":
+		w.wrap(b, n, "**", "**")
+	case "em", "i":
+		w.wrap(b, n, "__", "__")
+	case "code", "kbd", "samp", "tt":
+		// Unconstrained marks work inside words, as in foo(``x``)
+		if text := strings.TrimSpace(textContent(n)); text != "" {
+			b.WriteString("``" + text + "``")
+		}
+	case "sub":
+		w.wrap(b, n, "~", "~")
+	case "sup":
+		w.wrap(b, n, "^", "^")
+	case "del", "s", "strike":
+		w.wrap(b, n, "[.line-through]#", "#")
+	case "mark":
+		w.wrap(b, n, "##", "##")
+	case "a":
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "#") || w.opts.Links == LinkText {
+			w.children(b, n)
+			return
+		}
+		var text strings.Builder
+		w.children(&text, n)
+		label := strings.Join(strings.Fields(text.String()), " ")
+		if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") {
+			href = "link:" + href
+		}
+		b.WriteString(href + "[" + asciiDocAttr(label) + "]")
+	case "img":
+		switch w.opts.Images {
+		case ImageAlt:
+			b.WriteString(attr(n, "alt"))
+		case ImageDrop:
+		default:
+			if src := attr(n, "src"); src != "" {
+				b.WriteString("image:" + src + "[" + asciiDocAttr(attr(n, "alt")) + "]")
+			}
+		}
+	case inlineMathTag:
+		b.WriteString("latexmath:[" + strings.ReplaceAll(attr(n, texAttr), "]", `\]`) + "]")
+	case displayMathTag:
+		b.WriteString("\nlatexmath:[" + strings.ReplaceAll(attr(n, texAttr), "]", `\]`) + "]\n")
+	default:
+		w.children(b, n)
+	}
+}
+
+func (w *asciiDocWriter) children(b *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(b, c)
+	}
+}
+
+// wrap writes the content of n between AsciiDoc formatting marks, keeping
+// the spaces around the content outside of them
+func (w *asciiDocWriter) wrap(b *strings.Builder, n *html.Node, open, close string) {
+	var inner strings.Builder
+	w.children(&inner, n)
+	text := inner.String()
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		b.WriteString(text)
+		return
+	}
+	if strings.HasPrefix(text, " ") {
+		b.WriteString(" ")
+	}
+	b.WriteString(open + trimmed + close)
+	if strings.HasSuffix(text, " ") {
+		b.WriteString(" ")
+	}
+}
+
+// asciiDocAttr escapes the text of a macro attribute, such as link text
+func asciiDocAttr(text string) string {
+	return strings.ReplaceAll(text, "]", `\]`)
+}
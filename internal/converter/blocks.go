@@ -0,0 +1,274 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// BlockType names the kind of a Block
+type BlockType string
+
+const (
+	BlockHeading   BlockType = "heading"
+	BlockParagraph BlockType = "paragraph"
+	BlockList      BlockType = "list"
+	BlockListItem  BlockType = "list_item"
+	BlockCode      BlockType = "code"
+	BlockQuote     BlockType = "quote"
+	BlockTable     BlockType = "table"
+	BlockImage     BlockType = "image"
+	BlockMath      BlockType = "math"
+	BlockRule      BlockType = "rule"
+	BlockPage      BlockType = "page"
+)
+
+// Block is a structural element of a page, such as a heading, a paragraph
+// or a list. Text holds the plain text of the block, without markup.
+type Block struct {
+	Type     BlockType  `json:"type"`
+	Level    int        `json:"level,omitempty"`    // Heading level, 1 to 6
+	Text     string     `json:"text,omitempty"`     // Plain text, or the source of code and math
+	Language string     `json:"language,omitempty"` // Language of a code block
+	Ordered  bool       `json:"ordered,omitempty"`  // Whether a list is numbered
+	Header   bool       `json:"header,omitempty"`   // Whether the first table row is a header
+	Rows     [][]string `json:"rows,omitempty"`     // Cell texts of a table
+	Src      string     `json:"src,omitempty"`      // Source of an image
+	Alt      string     `json:"alt,omitempty"`      // Alternative text of an image
+	Page     int        `json:"page,omitempty"`     // Number of the PDF page a page marker opens
+	Children []*Block   `json:"children,omitempty"` // Items of a list, content of a quote or list item
+
+	inline []*html.Node // Inline content, kept for formats with markup
+}
+
+// Elements that start a block of their own
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"body": true, "center": true, "dd": true, "details": true, "dialog": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hgroup": true, "hr": true, "html": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"summary": true, "table": true, "ul": true, displayMathTag: true,
+	pageTag: true,
+}
+
+// Elements that never hold page content
+var skippedTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true,
+	"template": true, "iframe": true, "input": true, "textarea": true,
+	"select": true, "button": true,
+}
+
+// ExtractBlocks parses HTML content into its structural blocks
+func ExtractBlocks(content []byte) []*Block {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+	// Highlighted code and formulas are prepared as for Markdown
+	(&codePlugin{}).prepareCode(nil, doc)
+	(&mathPlugin{}).replaceMath(nil, doc)
+	return containerBlocks(doc)
+}
+
+// containerBlocks returns the blocks of an element's children, gathering
+// runs of inline content into paragraphs
+func containerBlocks(n *html.Node) []*Block {
+	var blocks []*Block
+	var inline []*html.Node
+	flush := func() {
+		if block := inlineBlock(inline); block != nil {
+			blocks = append(blocks, block)
+		}
+		inline = nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.ElementNode && skippedTags[c.Data]:
+		case c.Type == html.ElementNode && blockTags[c.Data]:
+			flush()
+			blocks = append(blocks, elementBlocks(c)...)
+		case c.Type == html.ElementNode || c.Type == html.TextNode:
+			inline = append(inline, c)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// inlineBlock makes a paragraph of inline content, or an image block when
+// the content is a single image
+func inlineBlock(nodes []*html.Node) *Block {
+	text := inlineText(nodes)
+	if text == "" {
+		var img *html.Node
+		for _, n := range nodes {
+			if n.Type == html.ElementNode && n.Data == "img" {
+				img = n
+			} else if n.Type == html.ElementNode {
+				img = findElement(n, func(n *html.Node) bool { return n.Data == "img" })
+			}
+			if img != nil {
+				break
+			}
+		}
+		if img == nil || attr(img, "src") == "" {
+			return nil
+		}
+		return &Block{Type: BlockImage, Src: attr(img, "src"), Alt: strings.TrimSpace(attr(img, "alt"))}
+	}
+	return &Block{Type: BlockParagraph, Text: text, inline: nodes}
+}
+
+func elementBlocks(n *html.Node) []*Block {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		nodes := children(n)
+		if text := inlineText(nodes); text != "" {
+			return []*Block{{Type: BlockHeading, Level: int(n.Data[1] - '0'), Text: text, inline: nodes}}
+		}
+		return nil
+	case "p", "dt", "summary", "figcaption":
+		// Paragraphs holding block elements are treated as containers
+		if findElement(n, func(c *html.Node) bool { return blockTags[c.Data] }) != nil {
+			return containerBlocks(n)
+		}
+		if block := inlineBlock(children(n)); block != nil {
+			return []*Block{block}
+		}
+		return nil
+	case "ul", "ol":
+		list := &Block{Type: BlockList, Ordered: n.Data == "ol"}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "li" {
+				list.Children = append(list.Children, listItem(c))
+			}
+		}
+		if len(list.Children) == 0 {
+			return nil
+		}
+		return []*Block{list}
+	case "pre":
+		code := &Block{Type: BlockCode, Text: strings.TrimRight(textContent(n), "\n"), Language: codeLanguage(n)}
+		if strings.TrimSpace(code.Text) == "" {
+			return nil
+		}
+		return []*Block{code}
+	case "blockquote":
+		if blocks := containerBlocks(n); len(blocks) > 0 {
+			return []*Block{{Type: BlockQuote, Children: blocks}}
+		}
+		return nil
+	case "table":
+		return tableBlocks(n)
+	case "hr":
+		return []*Block{{Type: BlockRule}}
+	case displayMathTag:
+		return []*Block{{Type: BlockMath, Text: attr(n, texAttr)}}
+	case pageTag:
+		return []*Block{{Type: BlockPage, Page: pageNumberOf(n)}}
+	}
+	return containerBlocks(n)
+}
+
+// listItem makes a list item, its first paragraph as its text and any
+// further blocks, such as nested lists, as its children
+func listItem(li *html.Node) *Block {
+	item := &Block{Type: BlockListItem}
+	blocks := containerBlocks(li)
+	if len(blocks) > 0 && blocks[0].Type == BlockParagraph {
+		item.Text, item.inline = blocks[0].Text, blocks[0].inline
+		blocks = blocks[1:]
+	}
+	item.Children = blocks
+	return item
+}
+
+func tableBlocks(n *html.Node) []*Block {
+	t := parseTable(n)
+	if len(t.rows) == 0 {
+		return nil
+	}
+	if t.isLayout(n) {
+		var blocks []*Block
+		for _, row := range t.rows {
+			for _, cell := range row {
+				blocks = append(blocks, containerBlocks(cell)...)
+			}
+		}
+		return blocks
+	}
+
+	block := &Block{Type: BlockTable, Header: t.header > 0}
+	if t.caption != nil {
+		block.Text = inlineText(children(t.caption))
+	}
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(inlineText(children(cell)), "\n", " ")
+		}
+		block.Rows = append(block.Rows, cells)
+	}
+	return []*Block{block}
+}
+
+// inlineText returns the plain text of inline content with its whitespace
+// collapsed, keeping line breaks and the TeX of formulas
+func inlineText(nodes []*html.Node) string {
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			// Line breaks in the source are only whitespace
+			b.WriteString(strings.Map(func(r rune) rune {
+				if unicode.IsSpace(r) {
+					return ' '
+				}
+				return r
+			}, n.Data))
+		case n.Type != html.ElementNode || skippedTags[n.Data]:
+		case n.Data == "br":
+			b.WriteString("\n")
+		case n.Data == inlineMathTag:
+			b.WriteString("$" + attr(n, texAttr) + "$")
+		case n.Data == displayMathTag:
+			b.WriteString("\n" + attr(n, texAttr) + "\n")
+		default:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				visit(c)
+			}
+		}
+	}
+	for _, n := range nodes {
+		visit(n)
+	}
+	return collapseText(b.String())
+}
+
+// collapseText collapses runs of whitespace into a space, or into a line
+// break when they hold one, and trims the result
+func collapseText(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func children(n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
@@ -0,0 +1,418 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// Classes of line number gutters written by syntax highlighters: Pygments,
+// Chroma, Rouge, highlight.js, GitHub, Expressive Code and
+// react-syntax-highlighter
+var gutterClasses = map[string]bool{
+	"linenos":                              true,
+	"linenodiv":                            true,
+	"lineno":                               true,
+	"ln":                                   true,
+	"lnt":                                  true,
+	"gutter":                               true,
+	"rouge-gutter":                         true,
+	"hljs-ln-numbers":                      true,
+	"blob-num":                             true,
+	"line-numbers-rows":                    true,
+	"react-syntax-highlighter-line-number": true,
+}
+
+// Classes of the elements highlighters wrap each line of code in
+var lineClasses = map[string]bool{
+	"line":         true,
+	"code-line":    true,
+	"token-line":   true,
+	"ec-line":      true,
+	"hljs-ln-line": true,
+}
+
+// Classes of table cells holding a line of code, or all of it: Pygments,
+// Rouge, highlight.js, GitHub and GitLab
+var codeClasses = map[string]bool{
+	"code":            true,
+	"rouge-code":      true,
+	"hljs-ln-code":    true,
+	"blob-code":       true,
+	"blob-code-inner": true,
+	"line-code":       true,
+}
+
+// Language names that mean the block is not highlighted
+var noLanguage = map[string]bool{
+	"none":        true,
+	"nohighlight": true,
+	"default":     true,
+}
+
+// Elements that end the search for a language among the ancestors of a <pre>
+var sectionElements = map[string]bool{
+	"html":    true,
+	"body":    true,
+	"main":    true,
+	"article": true,
+	"section": true,
+	"aside":   true,
+}
+
+// How many ancestors of a <pre> are searched for its language, enough for
+// the wrappers of Sphinx, GitHub, Docusaurus and VitePress
+const languageDepth = 4
+
+// codePlugin prepares the code blocks of syntax highlighters for the
+// commonmark plugin: it drops line number gutters, puts each line of code on
+// its own line and labels the block with its language
+type codePlugin struct{}
+
+func (p *codePlugin) Name() string {
+	return "md-fetch-code"
+}
+
+func (p *codePlugin) Init(conv *converter.Converter) error {
+	conv.Register.PreRenderer(p.prepareCode, converter.PriorityEarly)
+	return nil
+}
+
+func (p *codePlugin) prepareCode(ctx converter.Context, doc *html.Node) {
+	var gutters, tables, pres []*html.Node
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case isGutter(n) && inCodeContext(n):
+				gutters = append(gutters, n)
+				return
+			case n.Data == "table":
+				tables = append(tables, n)
+			case n.Data == "pre":
+				pres = append(pres, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+
+	for _, n := range gutters {
+		if n.Data == "td" || n.Data == "th" {
+			// Emptied, so the table is still seen as a gutter beside code
+			for n.FirstChild != nil {
+				n.RemoveChild(n.FirstChild)
+			}
+			continue
+		}
+		n.Parent.RemoveChild(n)
+	}
+	// Innermost tables first, as highlight.js nests its table in the <pre>
+	for i := len(tables) - 1; i >= 0; i-- {
+		unwrapCodeTable(tables[i])
+	}
+	for _, pre := range pres {
+		splitLines(pre)
+		if lang := codeLanguage(pre); lang != "" {
+			labelCode(pre, lang)
+		}
+	}
+}
+
+func isGutter(n *html.Node) bool {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		// CSS modules add a hash to the class, as in codeLineNumber_Y9uP
+		if gutterClasses[class] || strings.HasPrefix(class, "codeLineNumber") {
+			return true
+		}
+	}
+	return false
+}
+
+// inCodeContext reports whether an element with a gutter class belongs to a
+// highlighter: it is inside a code block, or is a table cell, or the content
+// of one, beside a cell holding code. The same class names are used for page
+// layout elsewhere, as in a "row gutter" grid.
+func inCodeContext(n *html.Node) bool {
+	if insideCode(n) {
+		return true
+	}
+	cell := n
+	if cell.Data != "td" && cell.Data != "th" {
+		cell = n.Parent
+	}
+	if cell == nil || cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+		return false
+	}
+	for c := cell.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c != cell && c.Type == html.ElementNode && isCodeCell(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCodeCell reports whether a table cell holds code, in a code block or, as
+// GitHub writes it, as text in a cell classed as code
+func isCodeCell(cell *html.Node) bool {
+	if cell.Data != "td" && cell.Data != "th" {
+		return false
+	}
+	if hasCodeClass(cell) {
+		return true
+	}
+	return findElement(cell, func(n *html.Node) bool { return n.Data == "pre" || n.Data == "code" }) != nil
+}
+
+// unwrapCodeTable replaces a table laying out line numbers beside code with
+// the code alone. Highlighters either put all the code in a single cell, as
+// Pygments, Chroma and Rouge do, or write one row per line, as highlight.js
+// and GitHub do.
+func unwrapCodeTable(table *html.Node) {
+	t := parseTable(table)
+	if len(t.rows) == 0 {
+		return
+	}
+
+	var cells []*html.Node
+	gutter := false
+	for _, row := range t.rows {
+		var code *html.Node
+		for _, cell := range row {
+			text := strings.TrimSpace(textContent(cell))
+			if text == "" || isLineNumbers(text) {
+				gutter = true
+				continue
+			}
+			if code != nil {
+				return // a data table with several columns
+			}
+			code = cell
+		}
+		if code == nil {
+			code = row[len(row)-1] // an empty line
+		}
+		cells = append(cells, code)
+	}
+
+	// Gutters found by their class are already removed, which leaves
+	// highlight.js tables inside the <code> with a single column
+	switch {
+	case !gutter && !insideCode(table):
+		return
+	case len(cells) == 1 && findElement(cells[0], func(n *html.Node) bool { return n.Data == "pre" }) == nil:
+		return
+	case len(cells) > 1 && !insideCode(table) && !codeCells(cells):
+		return
+	}
+
+	parent := table.Parent
+	if len(cells) == 1 {
+		for c := cells[0].FirstChild; c != nil; {
+			next := c.NextSibling
+			cells[0].RemoveChild(c)
+			parent.InsertBefore(c, table)
+			c = next
+		}
+		parent.RemoveChild(table)
+		return
+	}
+
+	lines := make([]string, len(cells))
+	for i, cell := range cells {
+		lines[i] = strings.TrimRight(textContent(cell), "\n")
+	}
+	text := &html.Node{Type: html.TextNode, Data: strings.Join(lines, "\n")}
+	if insideCode(table) {
+		parent.InsertBefore(text, table)
+	} else {
+		pre := &html.Node{Type: html.ElementNode, Data: "pre"}
+		code := &html.Node{Type: html.ElementNode, Data: "code"}
+		code.AppendChild(text)
+		pre.AppendChild(code)
+		parent.InsertBefore(pre, table)
+	}
+	parent.RemoveChild(table)
+}
+
+func isLineNumbers(text string) bool {
+	for _, r := range text {
+		if (r < '0' || r > '9') && r != '\n' && r != ' ' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// codeCells reports whether the cells are marked as lines of code, as in
+// GitHub's blob-code
+func codeCells(cells []*html.Node) bool {
+	for _, cell := range cells {
+		if !hasCodeClass(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasCodeClass reports whether an element has one of codeClasses. Whole
+// classes are compared, as postcode or country-code name data columns.
+func hasCodeClass(n *html.Node) bool {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if codeClasses[class] {
+			return true
+		}
+	}
+	return false
+}
+
+func insideCode(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && (p.Data == "pre" || p.Data == "code") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLines ends every line element of a code block with a newline.
+// Some highlighters wrap lines in elements without newlines between them,
+// relying on CSS to break the lines, and block elements such as <div> would
+// otherwise start a line instead of ending one.
+func splitLines(pre *html.Node) {
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			visit(c)
+
+			line := isLineElement(c) || (c.Data == "div" && findElement(c, func(n *html.Node) bool { return n.Data == "div" }) == nil)
+			if c.Data == "div" {
+				c.Data = "span"
+				c.DataAtom = 0
+			}
+			if line && !strings.HasSuffix(textContent(c), "\n") && !startsWithNewline(c.NextSibling) {
+				c.AppendChild(&html.Node{Type: html.TextNode, Data: "\n"})
+			}
+		}
+	}
+	visit(pre)
+}
+
+func isLineElement(n *html.Node) bool {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if lineClasses[class] {
+			return true
+		}
+	}
+	return false
+}
+
+func startsWithNewline(n *html.Node) bool {
+	return n != nil && n.Type == html.TextNode && strings.HasPrefix(n.Data, "\n")
+}
+
+// codeLanguage infers the language of a code block from the <pre>, its
+// <code> and the wrappers around them
+func codeLanguage(pre *html.Node) string {
+	candidates := []*html.Node{pre}
+	if code := findElement(pre, func(n *html.Node) bool { return n.Data == "code" }); code != nil {
+		candidates = append(candidates, code)
+	}
+	// Page sections are not code wrappers
+	for p, depth := pre.Parent, 0; p != nil && p.Type == html.ElementNode && depth < languageDepth; p, depth = p.Parent, depth+1 {
+		if sectionElements[p.Data] {
+			break
+		}
+		candidates = append(candidates, p)
+	}
+
+	for _, n := range candidates {
+		if lang := elementLanguage(n); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+func elementLanguage(n *html.Node) string {
+	for _, key := range []string{"data-lang", "data-language"} {
+		if lang := normalizeLanguage(attr(n, key)); lang != "" {
+			return lang
+		}
+	}
+
+	classes := strings.Fields(attr(n, "class"))
+	for i, class := range classes {
+		var lang string
+		switch {
+		case strings.HasPrefix(class, "language-"):
+			lang = strings.TrimPrefix(class, "language-")
+		case strings.HasPrefix(class, "lang-") && (n.Data == "pre" || n.Data == "code"):
+			// Wrappers use lang- for the language of the page, as in lang-en
+			lang = strings.TrimPrefix(class, "lang-")
+		case strings.HasPrefix(class, "highlight-source-"):
+			lang = strings.TrimPrefix(class, "highlight-source-") // GitHub
+		case strings.HasPrefix(class, "highlight-"):
+			lang = strings.TrimPrefix(class, "highlight-") // Sphinx
+		case strings.HasPrefix(class, "brush:"):
+			lang = strings.TrimPrefix(class, "brush:") // SyntaxHighlighter
+			if lang == "" && i+1 < len(classes) {
+				lang = classes[i+1]
+			}
+		case class == "sourceCode" && n.Data == "pre" && i+1 < len(classes):
+			lang = classes[i+1] // Pandoc
+		}
+		if lang = normalizeLanguage(lang); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.Trim(strings.TrimSpace(lang), ";,"))
+	if noLanguage[lang] || strings.ContainsAny(lang, "` \t\n") {
+		return ""
+	}
+	return lang
+}
+
+// labelCode sets the language of a code block where the commonmark plugin
+// reads it, the class of the <pre>
+func labelCode(pre *html.Node, lang string) {
+	setAttr(pre, "class", "language-"+lang)
+	for c := pre.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "code" {
+			setAttr(c, "class", "")
+		}
+	}
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func findElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+		if found := findElement(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
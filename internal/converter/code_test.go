@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name:     "language class",
+			html:     `<pre><code class="language-go">fmt.Println("hi")</code></pre>`,
+			expected: "```go\nfmt.Println(\"hi\")\n```",
+		},
+		{
+			name:     "prism tokens",
+			html:     `<pre class="language-js line-numbers"><code class="language-js"><span class="token keyword">const</span> x <span class="token operator">=</span> <span class="token number">1</span>;<span aria-hidden="true" class="line-numbers-rows"><span></span></span></code></pre>`,
+			expected: "```js\nconst x = 1;\n```",
+		},
+		{
+			name:     "highlight.js",
+			html:     `<pre><code class="hljs language-Python"><span class="hljs-keyword">def</span> <span class="hljs-title">f</span>():</code></pre>`,
+			expected: "```python\ndef f():\n```",
+		},
+		{
+			name:     "github wrapper",
+			html:     `<div class="highlight highlight-source-rust notranslate"><pre><span class="pl-k">fn</span> main() {}</pre></div>`,
+			expected: "```rust\nfn main() {}\n```",
+		},
+		{
+			name:     "data-lang",
+			html:     `<div class="highlight"><pre tabindex="0" class="chroma"><code class="language-sh" data-lang="bash"><span class="line"><span class="cl">ls</span></span></code></pre></div>`,
+			expected: "```bash\nls\n```",
+		},
+		{
+			name: "pygments line numbers table",
+			html: `<div class="highlight-python notranslate"><table class="highlighttable"><tr><td class="linenos"><div class="linenodiv"><pre>1
+2</pre></div></td><td class="code"><div class="highlight"><pre><span></span><span class="n">x</span> <span class="o">=</span> <span class="mi">1</span>
+<span class="nb">print</span><span class="p">(</span><span class="n">x</span><span class="p">)</span>
+</pre></div></td></tr></table></div>`,
+			expected: "```python\nx = 1\nprint(x)\n```",
+		},
+		{
+			name: "chroma line numbers table",
+			html: `<div class="highlight"><div class="chroma"><table class="lntable"><tr><td class="lntd"><pre tabindex="0" class="chroma"><code><span class="lnt">1
+</span><span class="lnt">2
+</span></code></pre></td><td class="lntd"><pre tabindex="0" class="chroma"><code class="language-go" data-lang="go"><span class="line"><span class="cl">a := 1
+</span></span><span class="line"><span class="cl">b := 2
+</span></span></code></pre></td></tr></table></div></div>`,
+			expected: "```go\na := 1\nb := 2\n```",
+		},
+		{
+			name: "inline line numbers",
+			html: `<pre class="chroma"><code class="language-go"><span class="line"><span class="ln">1</span><span class="cl">a := 1
+</span></span><span class="line"><span class="ln">2</span><span class="cl">b := 2</span></span></code></pre>`,
+			expected: "```go\na := 1\nb := 2\n```",
+		},
+		{
+			name:     "highlight.js line numbers plugin",
+			html:     `<pre><code class="hljs language-js"><table class="hljs-ln"><tr><td class="hljs-ln-line hljs-ln-numbers"><div class="hljs-ln-n" data-line-number="1"></div></td><td class="hljs-ln-line hljs-ln-code" data-line-number="1">let a;</td></tr><tr><td class="hljs-ln-line hljs-ln-numbers"><div class="hljs-ln-n" data-line-number="2"></div></td><td class="hljs-ln-line hljs-ln-code" data-line-number="2">let b;</td></tr></table></code></pre>`,
+			expected: "```js\nlet a;\nlet b;\n```",
+		},
+		{
+			name:     "shiki lines without newlines",
+			html:     `<div class="language-ts"><pre class="shiki"><code><span class="line"><span style="color:#F97583">const</span> a = 1</span><span class="line">a++</span></code></pre></div>`,
+			expected: "```ts\nconst a = 1\na++\n```",
+		},
+		{
+			name:     "line divs",
+			html:     `<pre data-language="yaml"><div class="ec-line"><div class="gutter"><div class="ln">1</div></div><div class="code">a: 1</div></div><div class="ec-line"><div class="gutter"><div class="ln">2</div></div><div class="code">b: 2</div></div></pre>`,
+			expected: "```yaml\na: 1\nb: 2\n```",
+		},
+		{
+			name:     "page language is not a code language",
+			html:     `<body class="lang-en"><pre>plain</pre></body>`,
+			expected: "```\nplain\n```",
+		},
+		{
+			name:     "wrapper language is not a code language",
+			html:     `<div class="post lang-en"><pre>plain</pre></div>`,
+			expected: "```\nplain\n```",
+		},
+		{
+			name:     "code language on the pre",
+			html:     `<div class="lang-en"><pre class="lang-ruby">puts 1</pre></div>`,
+			expected: "```ruby\nputs 1\n```",
+		},
+		{
+			name:     "layout gutter class is not a code gutter",
+			html:     `<div class="row gutter"><p>Main article text</p></div><p>tail</p>`,
+			expected: "Main article text\n\ntail",
+		},
+		{
+			name:     "github blob table",
+			html:     `<table class="highlight"><tr><td class="blob-num" data-line-number="1">1</td><td class="blob-code blob-code-inner">a = 1</td></tr><tr><td class="blob-num">2</td><td class="blob-code blob-code-inner">b = 2</td></tr></table>`,
+			expected: "```\na = 1\nb = 2\n```",
+		},
+		{
+			name:     "data table with numbers is kept",
+			html:     `<table><tr><th>Code</th><th>Meaning</th></tr><tr><td>200</td><td><code>OK</code></td></tr></table>`,
+			expected: "| Code | Meaning |\n| --- | --- |\n| 200 | `OK` |",
+		},
+		{
+			name:     "data table with code in class names is kept",
+			html:     `<table><tr><td class="ln">1</td><td class="postcode">SW1A 1AA</td></tr><tr><td class="ln">2</td><td class="postcode">EC1A 1BB</td></tr></table>`,
+			expected: "|  |  |\n| --- | --- |\n| 1 | SW1A 1AA |\n| 2 | EC1A 1BB |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ConvertToMarkdown([]byte(tt.html))
+			if result != tt.expected {
+				t.Errorf("\nexpected:\n%q\ngot:\n%q", tt.expected, result)
+			}
+		})
+	}
+}
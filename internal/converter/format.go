@@ -0,0 +1,44 @@
+package converter
+
+// Format selects the output format of a fetched page
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown" // Markdown, the default
+	FormatText     Format = "text"     // plain prose without markup
+	FormatHTML     Format = "html"     // the cleaned HTML
+	FormatJSON     Format = "json"     // structured blocks with the page metadata
+	FormatAsciiDoc Format = "asciidoc" // AsciiDoc
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{FormatMarkdown, FormatText, FormatHTML, FormatJSON, FormatAsciiDoc}
+
+// Valid reports whether f is a known format, the empty format meaning
+// Markdown
+func (f Format) Valid() bool {
+	if f == "" {
+		return true
+	}
+	for _, format := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Extension returns the file extension of the format, with its dot
+func (f Format) Extension() string {
+	switch f {
+	case FormatText:
+		return ".txt"
+	case FormatHTML:
+		return ".html"
+	case FormatJSON:
+		return ".json"
+	case FormatAsciiDoc:
+		return ".adoc"
+	}
+	return ".md"
+}
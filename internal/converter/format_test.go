@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const formatHTML = `<html><head><title>Ignored</title></head><body>
+	<h1>Guide</h1>
+	<p>Some <b>bold</b> and <a href="https://example.com/a">a link</a>,
+	with <code>f(x)</code>.<br>Next line.</p>
+	<ul><li>One<ul><li>Nested</li></ul></li><li><p>Two</p><pre><code class="language-go">x := 1</code></pre></li></ul>
+	<blockquote><p>Quoted</p></blockquote>
+	<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>
+	<p><img src="https://example.com/pic.png" alt="Pic"></p>
+</body></html>`
+
+func TestConvertToText(t *testing.T) {
+	expected := "Guide\n\n" +
+		"Some bold and a link, with f(x).\nNext line.\n\n" +
+		"- One\n  - Nested\n- Two\n  x := 1\n\n" +
+		"  Quoted\n\n" +
+		"A\tB\n1\t2\n\n" +
+		"Pic"
+
+	if result := ConvertToText([]byte(formatHTML)); result != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, result)
+	}
+}
+
+func TestConvertToAsciiDoc(t *testing.T) {
+	expected := "== Guide\n\n" +
+		"Some **bold** and https://example.com/a[a link], with ``f(x)``. +\nNext line.\n\n" +
+		"* One\n** Nested\n* Two\n+\n[source,go]\n----\nx := 1\n----\n\n" +
+		"____\nQuoted\n____\n\n" +
+		"[%header]\n|===\n| A | B\n| 1 | 2\n|===\n\n" +
+		"image::https://example.com/pic.png[Pic]"
+
+	if result := ConvertToAsciiDoc([]byte(formatHTML), DefaultOptions()); result != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, result)
+	}
+
+	result := ConvertToAsciiDoc([]byte(`<p>See <a href="/docs">the docs</a> <img src="x.png" alt="X"></p>`), &Options{Links: LinkText, Images: ImageAlt})
+	if expected := "See the docs X"; result != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, result)
+	}
+}
+
+func TestExtractBlocks(t *testing.T) {
+	out, err := json.Marshal(ExtractBlocks([]byte(formatHTML)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[` +
+		`{"type":"heading","level":1,"text":"Guide"},` +
+		`{"type":"paragraph","text":"Some bold and a link, with f(x).\nNext line."},` +
+		`{"type":"list","children":[` +
+		`{"type":"list_item","text":"One","children":[{"type":"list","children":[{"type":"list_item","text":"Nested"}]}]},` +
+		`{"type":"list_item","text":"Two","children":[{"type":"code","text":"x := 1","language":"go"}]}]},` +
+		`{"type":"quote","children":[{"type":"paragraph","text":"Quoted"}]},` +
+		`{"type":"table","header":true,"rows":[["A","B"],["1","2"]]},` +
+		`{"type":"image","src":"https://example.com/pic.png","alt":"Pic"}` +
+		`]`
+	if string(out) != expected {
+		t.Errorf("\nexpected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if !Format("").Valid() || !FormatAsciiDoc.Valid() || Format("pdf").Valid() {
+		t.Error("unexpected format validity")
+	}
+	if FormatAsciiDoc.Extension() != ".adoc" || Format("").Extension() != ".md" {
+		t.Error("unexpected format extension")
+	}
+}
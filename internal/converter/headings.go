@@ -0,0 +1,217 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// headingPlugin makes the headings of a page a proper outline: a single
+// H1, no skipped levels and no empty headings
+type headingPlugin struct{}
+
+func (p *headingPlugin) Name() string {
+	return "md-fetch-headings"
+}
+
+func (p *headingPlugin) Init(conv *converter.Converter) error {
+	conv.Register.PreRenderer(p.normalizeHeadings, converter.PriorityEarly)
+	return nil
+}
+
+func (p *headingPlugin) normalizeHeadings(ctx converter.Context, doc *html.Node) {
+	var headings []*html.Node
+	walkElements(doc, func(n *html.Node) bool {
+		if headingLevel(n) == 0 {
+			return true
+		}
+		if strings.TrimSpace(textContent(n)) == "" && findElement(n, func(n *html.Node) bool { return n.Data == "img" }) == nil {
+			n.Parent.RemoveChild(n)
+		} else {
+			headings = append(headings, n)
+		}
+		return false
+	})
+
+	levels := make([]int, len(headings))
+	for i, n := range headings {
+		levels[i] = headingLevel(n)
+	}
+	for i, level := range normalizeLevels(levels) {
+		tag := fmt.Sprintf("h%d", level)
+		headings[i].Data, headings[i].DataAtom = tag, atom.Lookup([]byte(tag))
+	}
+}
+
+// normalizeLevels maps the heading levels of a page, in document order, to
+// an outline. The first of the highest headings becomes the only level 1,
+// other headings at the top of the outline, before or after it, become
+// level 2, and a heading is at most one level below the heading it follows.
+func normalizeLevels(levels []int) []int {
+	title := -1
+	for i, level := range levels {
+		if title < 0 || level < levels[title] {
+			title = i
+		}
+	}
+
+	type open struct{ level, normalized int }
+	var stack []open
+	normalized := make([]int, len(levels))
+	for i, level := range levels {
+		for len(stack) > 0 && stack[len(stack)-1].level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		switch {
+		case i == title:
+			normalized[i], stack = 1, nil
+		case len(stack) == 0:
+			normalized[i] = 2
+		default:
+			normalized[i] = min(stack[len(stack)-1].normalized+1, 6)
+		}
+		stack = append(stack, open{level, normalized[i]})
+	}
+	return normalized
+}
+
+// NormalizeHeadings rewrites the levels of heading blocks into an outline,
+// as the normalize_headings option does for Markdown
+func NormalizeHeadings(blocks []*Block) {
+	var headings []*Block
+	var visit func([]*Block)
+	visit = func(blocks []*Block) {
+		for _, block := range blocks {
+			if block.Type == BlockHeading {
+				headings = append(headings, block)
+			}
+			visit(block.Children)
+		}
+	}
+	visit(blocks)
+
+	levels := make([]int, len(headings))
+	for i, block := range headings {
+		levels[i] = block.Level
+	}
+	for i, level := range normalizeLevels(levels) {
+		headings[i].Level = level
+	}
+}
+
+func headingLevel(n *html.Node) int {
+	if n.Type == html.ElementNode && len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6' {
+		return int(n.Data[1] - '0')
+	}
+	return 0
+}
+
+var (
+	atxHeading    = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t]*$`)
+	markdownImage = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink  = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	escapedChar   = regexp.MustCompile(`\\([[:punct:]])`)
+)
+
+// tocHeading is a heading of the Markdown output
+type tocHeading struct {
+	level int
+	text  string
+	line  int
+}
+
+// insertTableOfContents adds a list of links to the headings of the
+// Markdown, after the H1 the Markdown opens with or else at its top. The
+// anchors are the ones GitHub and most renderers generate.
+func insertTableOfContents(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	var headings []tocHeading
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			text := markdownLink.ReplaceAllString(markdownImage.ReplaceAllString(m[2], "$1"), "$1")
+			headings = append(headings, tocHeading{level: len(m[1]), text: text, line: i})
+		}
+	}
+
+	// The title the Markdown opens with is not listed
+	insertAt := 0
+	if len(headings) > 0 && headings[0].level == 1 && strings.TrimSpace(strings.Join(lines[:headings[0].line], "")) == "" {
+		insertAt = headings[0].line + 1
+		headings = headings[1:]
+	}
+	if len(headings) == 0 {
+		return markdown
+	}
+
+	top := 6
+	for _, h := range headings {
+		top = min(top, h.level)
+	}
+	used := map[string]int{}
+	var toc []string
+	depth := -1
+	for _, h := range headings {
+		// A list item can only be nested one level below the previous one
+		depth = min(h.level-top, depth+1)
+		anchor := headingAnchor(plainHeading(h.text))
+		if n := used[anchor]; n > 0 {
+			used[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, n)
+		} else {
+			used[anchor] = 1
+		}
+		toc = append(toc, fmt.Sprintf("%s- [%s](#%s)", strings.Repeat("  ", depth), h.text, anchor))
+	}
+
+	var out []string
+	out = append(out, lines[:insertAt]...)
+	if insertAt > 0 {
+		out = append(out, "")
+	}
+	out = append(out, toc...)
+	out = append(out, "")
+	rest := lines[insertAt:]
+	for len(rest) > 0 && strings.TrimSpace(rest[0]) == "" {
+		rest = rest[1:]
+	}
+	return strings.Join(append(out, rest...), "\n")
+}
+
+// plainHeading returns the text of a Markdown heading without emphasis,
+// code spans, escapes and entities
+func plainHeading(text string) string {
+	text = strings.NewReplacer("**", "", "*", "", "`", "").Replace(text)
+	return html.UnescapeString(escapedChar.ReplaceAllString(text, "$1"))
+}
+
+// headingAnchor returns the anchor of a heading: its lowercase text without
+// punctuation, spaces turned into hyphens
+func headingAnchor(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
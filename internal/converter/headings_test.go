@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		levels   []int
+		expected []int
+	}{
+		{"outline", []int{1, 2, 3, 2}, []int{1, 2, 3, 2}},
+		{"skipped levels", []int{1, 3, 5, 3}, []int{1, 2, 3, 2}},
+		{"multiple h1", []int{1, 2, 1, 2}, []int{1, 2, 2, 3}},
+		{"heading before the title", []int{2, 1, 2}, []int{2, 1, 2}},
+		{"no h1", []int{2, 3, 2, 4}, []int{1, 2, 2, 3}},
+		{"deep nesting", []int{1, 2, 3, 4, 5, 6}, []int{1, 2, 3, 4, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeLevels(tt.levels); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeHeadings(t *testing.T) {
+	html := `<h3>Site</h3><h1>Guide</h1><h1>Install</h1><h4>Linux</h4><p>Text.</p><h2> </h2><h2>Usage</h2>`
+
+	expected := "## Site\n\n# Guide\n\n## Install\n\n### Linux\n\nText.\n\n### Usage"
+	if result := ConvertToMarkdownWithOptions([]byte(html), &Options{NormalizeHeadings: true}); result != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, result)
+	}
+
+	blocks := ExtractBlocks([]byte(html))
+	NormalizeHeadings(blocks)
+	var levels []int
+	for _, block := range blocks {
+		if block.Type == BlockHeading {
+			levels = append(levels, block.Level)
+		}
+	}
+	if !reflect.DeepEqual(levels, []int{2, 1, 2, 3, 3}) {
+		t.Errorf("expected normalized block levels, got %v", levels)
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	html := `<h1>Guide</h1><p>Intro.</p><h2>Getting <em>started</em></h2><h3>C++ &amp; Go</h3><pre><code># not a heading</code></pre><h2><a href="/faq">FAQ</a></h2><h2>FAQ</h2>`
+
+	expected := "# Guide\n\n" +
+		"- [Getting *started*](#getting-started)\n" +
+		"  - [C++ &amp; Go](#c--go)\n" +
+		"- [FAQ](#faq)\n" +
+		"- [FAQ](#faq-1)\n\n" +
+		"Intro.\n\n## Getting *started*\n\n### C++ &amp; Go\n\n```\n# not a heading\n```\n\n## [FAQ](/faq)\n\n## FAQ"
+	if result := ConvertToMarkdownWithOptions([]byte(html), &Options{TOC: true}); result != expected {
+		t.Errorf("\nexpected:\n%s\ngot:\n%s", expected, result)
+	}
+
+	// Items are never nested more than one level below the previous one
+	result := ConvertToMarkdownWithOptions([]byte(`<h1>Guide</h1><h3>Deep</h3><h2>Two</h2>`), &Options{TOC: true})
+	if expected := "# Guide\n\n- [Deep](#deep)\n- [Two](#two)\n\n### Deep\n\n## Two"; result != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, result)
+	}
+
+	// Without a title the list opens the Markdown
+	result = ConvertToMarkdownWithOptions([]byte(`<h2>One</h2><p>a</p><h2>Two</h2>`), &Options{TOC: true})
+	if expected := "- [One](#one)\n- [Two](#two)\n\n## One\n\na\n\n## Two"; result != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, result)
+	}
+}
@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// ImageMode selects how images are rendered
+type ImageMode string
+
+const (
+	ImageKeep     ImageMode = "keep"     // ![alt](url)
+	ImageAlt      ImageMode = "alt"      // alt text only
+	ImageDrop     ImageMode = "drop"     // images are removed
+	ImageDownload ImageMode = "download" // ![alt](local path), using Options.DownloadImage
+)
+
+// ImageModes lists the supported image modes
+var ImageModes = []ImageMode{ImageKeep, ImageAlt, ImageDrop, ImageDownload}
+
+func (m ImageMode) valid() bool {
+	if m == "" {
+		return true
+	}
+	for _, mode := range ImageModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// imagePlugin renders images for every mode but keep, which is left to the
+// commonmark plugin
+type imagePlugin struct {
+	mode     ImageMode
+	download func(src string) (string, error)
+}
+
+func (p *imagePlugin) Name() string {
+	return "md-fetch-images"
+}
+
+func (p *imagePlugin) Init(conv *converter.Converter) error {
+	switch p.mode {
+	case ImageAlt, ImageDrop:
+		// Replacing images before whitespace is collapsed keeps the
+		// surrounding text evenly spaced
+		conv.Register.PreRenderer(p.replaceImages, converter.PriorityEarly)
+	case ImageDownload:
+		conv.Register.RendererFor("img", converter.TagTypeInline, p.renderDownloaded, converter.PriorityEarly)
+	}
+	return nil
+}
+
+// replaceImages swaps every image for its alt text, or removes it
+func (p *imagePlugin) replaceImages(ctx converter.Context, doc *html.Node) {
+	var images []*html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			images = append(images, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+
+	for _, img := range images {
+		if alt := strings.Join(strings.Fields(attr(img, "alt")), " "); p.mode == ImageAlt && alt != "" {
+			img.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: alt}, img)
+		}
+		img.Parent.RemoveChild(img)
+	}
+}
+
+// renderDownloaded points the image at its downloaded copy and lets
+// commonmark render it. Images that cannot be downloaded keep their remote
+// URL.
+func (p *imagePlugin) renderDownloaded(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	src := strings.TrimSpace(attr(n, "src"))
+	if src != "" && p.download != nil {
+		if local, err := p.download(src); err == nil {
+			setAttr(n, "src", local)
+		}
+	}
+	return converter.RenderTryNext
+}
+
+func setAttr(n *html.Node, key, value string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
@@ -0,0 +1,36 @@
+package converter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestImageModes(t *testing.T) {
+	html := `<p>Architecture <img src="https://example.com/diagram.png" alt="System diagram"> and <img src="https://example.com/missing.png" alt="Missing"></p>`
+
+	download := func(src string) (string, error) {
+		if src == "https://example.com/diagram.png" {
+			return "assets/0123abcd.png", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	tests := []struct {
+		mode     ImageMode
+		expected string
+	}{
+		{ImageKeep, "Architecture ![System diagram](https://example.com/diagram.png) and ![Missing](https://example.com/missing.png)"},
+		{ImageAlt, "Architecture System diagram and Missing"},
+		{ImageDrop, "Architecture and"},
+		{ImageDownload, "Architecture ![System diagram](assets/0123abcd.png) and ![Missing](https://example.com/missing.png)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			result := ConvertToMarkdownWithOptions([]byte(html), &Options{Images: tt.mode, DownloadImage: download})
+			if result != tt.expected {
+				t.Errorf("\nexpected:\n%q\ngot:\n%q", tt.expected, result)
+			}
+		})
+	}
+}
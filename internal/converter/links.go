@@ -0,0 +1,144 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// LinkMode selects how links are rendered
+type LinkMode string
+
+const (
+	LinkInline    LinkMode = "inline"    // [text](url)
+	LinkReference LinkMode = "reference" // [text][1], with numbered definitions at the end
+	LinkText      LinkMode = "text"      // text only, links are dropped
+	LinkAppendix  LinkMode = "appendix"  // text only, with a numbered list of links at the end
+)
+
+// LinkModes lists the supported link modes
+var LinkModes = []LinkMode{LinkInline, LinkReference, LinkText, LinkAppendix}
+
+func (m LinkMode) valid() bool {
+	if m == "" {
+		return true
+	}
+	for _, mode := range LinkModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// linkPlugin renders links for every mode but inline, which is left to the
+// commonmark plugin. Links are numbered in order of appearance and a URL
+// linked several times keeps its first number.
+type linkPlugin struct {
+	mode    LinkMode
+	urls    []string
+	texts   []string
+	numbers map[string]int
+}
+
+func newLinkPlugin(mode LinkMode) *linkPlugin {
+	return &linkPlugin{mode: mode, numbers: make(map[string]int)}
+}
+
+func (p *linkPlugin) Name() string {
+	return "md-fetch-links"
+}
+
+func (p *linkPlugin) Init(conv *converter.Converter) error {
+	if p.mode != "" && p.mode != LinkInline {
+		conv.Register.RendererFor("a", converter.TagTypeInline, p.renderLink, converter.PriorityEarly)
+	}
+	return nil
+}
+
+func (p *linkPlugin) renderLink(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	var buf bytes.Buffer
+	ctx.RenderChildNodes(ctx, &buf, n)
+	content := buf.Bytes()
+
+	href := strings.TrimSpace(attr(n, "href"))
+	text := collapseLines(strings.TrimSpace(string(content)))
+	if href == "" || href == "#" || p.mode == LinkText || text == "" {
+		w.Write(content)
+		return converter.RenderSuccess
+	}
+
+	number, ok := p.numbers[href]
+	if !ok {
+		p.urls = append(p.urls, href)
+		p.texts = append(p.texts, text)
+		number = len(p.urls)
+		p.numbers[href] = number
+	}
+
+	// Keep the spacing around the link text outside the brackets
+	before := content[:len(content)-len(bytes.TrimLeft(content, " \t\n"))]
+	after := content[len(bytes.TrimRight(content, " \t\n")):]
+
+	w.Write(before)
+	switch p.mode {
+	case LinkReference:
+		fmt.Fprintf(w, "[%s][%d]", text, number)
+	case LinkAppendix:
+		fmt.Fprintf(w, "%s [%d]", text, number)
+	}
+	w.Write(after)
+	return converter.RenderSuccess
+}
+
+// appendLinks adds the collected links after the converted Markdown
+func (p *linkPlugin) appendLinks(markdown string) string {
+	if len(p.urls) == 0 {
+		return markdown
+	}
+
+	var b strings.Builder
+	b.WriteString(markdown)
+	switch p.mode {
+	case LinkReference:
+		b.WriteString("\n")
+		for i, url := range p.urls {
+			fmt.Fprintf(&b, "\n[%d]: %s", i+1, formatDestination(url))
+		}
+	case LinkAppendix:
+		b.WriteString("\n\n## Links\n")
+		for i, url := range p.urls {
+			fmt.Fprintf(&b, "\n%d. [%s](%s)", i+1, p.texts[i], formatDestination(url))
+		}
+	}
+	return b.String()
+}
+
+// formatDestination wraps link destinations containing spaces or
+// parentheses in angle brackets, as CommonMark requires
+func formatDestination(url string) string {
+	if strings.ContainsAny(url, " ()") {
+		return "<" + url + ">"
+	}
+	return url
+}
+
+// collapseLines joins multi-line link text into one line
+func collapseLines(text string) string {
+	if !strings.ContainsAny(text, "\r\n") {
+		return text
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
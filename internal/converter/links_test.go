@@ -0,0 +1,49 @@
+package converter
+
+import (
+	"testing"
+)
+
+func TestLinkModes(t *testing.T) {
+	html := `<p>Read the <a href="https://example.com/guide">guide</a>, the <a href="https://example.com/faq">FAQ</a> and the <a href="https://example.com/guide">guide again</a>. <a href="#top">Top</a></p>`
+
+	tests := []struct {
+		mode     LinkMode
+		expected string
+	}{
+		{
+			mode:     LinkInline,
+			expected: "Read the [guide](https://example.com/guide), the [FAQ](https://example.com/faq) and the [guide again](https://example.com/guide). [Top](#top)",
+		},
+		{
+			mode:     LinkReference,
+			expected: "Read the [guide][1], the [FAQ][2] and the [guide again][1]. [Top][3]\n\n[1]: https://example.com/guide\n[2]: https://example.com/faq\n[3]: #top",
+		},
+		{
+			mode:     LinkText,
+			expected: "Read the guide, the FAQ and the guide again. Top",
+		},
+		{
+			mode:     LinkAppendix,
+			expected: "Read the guide [1], the FAQ [2] and the guide again [1]. Top [3]\n\n## Links\n\n1. [guide](https://example.com/guide)\n2. [FAQ](https://example.com/faq)\n3. [Top](#top)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			result := ConvertToMarkdownWithOptions([]byte(html), &Options{Links: tt.mode})
+			if result != tt.expected {
+				t.Errorf("\nexpected:\n%q\ngot:\n%q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	if err := (&Options{Links: "footnotes"}).Validate(); err == nil {
+		t.Error("expected error for unknown links mode")
+	}
+	if err := (&Options{}).Validate(); err != nil {
+		t.Errorf("expected empty options to be valid, got %v", err)
+	}
+}
@@ -1,17 +1,86 @@
 package converter
 
 import (
-	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"fmt"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
 )
 
+// Options configures how HTML is rendered as Markdown. The struct tags name
+// the options in API requests and the configuration file.
+type Options struct {
+	Links  LinkMode  `json:"links,omitempty" yaml:"links"`   // How links are rendered, inline by default
+	Images ImageMode `json:"images,omitempty" yaml:"images"` // How images are rendered, kept by default
+
+	ComplexTables TableMode `json:"complex_tables,omitempty" yaml:"complex_tables"` // How tables that do not fit a pipe table are rendered, as HTML by default
+
+	NormalizeHeadings bool `json:"normalize_headings,omitempty" yaml:"normalize_headings"` // Rewrite heading levels into an outline with a single H1
+	TOC               bool `json:"toc,omitempty" yaml:"toc"`                               // Add a table of contents linking to the headings of Markdown
+
+	// DownloadImage saves the image at src and returns the path the
+	// Markdown should link to. It is required by the download image mode.
+	DownloadImage func(src string) (string, error) `json:"-" yaml:"-"`
+}
+
+// DefaultOptions returns the default conversion configuration
+func DefaultOptions() *Options {
+	return &Options{
+		Links:  LinkInline,
+		Images: ImageKeep,
+
+		ComplexTables: TableHTML,
+	}
+}
+
+// Validate reports whether the options hold known values
+func (o *Options) Validate() error {
+	if !o.Links.valid() {
+		return fmt.Errorf("invalid links mode %q: must be one of %s", o.Links, LinkModes)
+	}
+	if !o.Images.valid() {
+		return fmt.Errorf("invalid images mode %q: must be one of %s", o.Images, ImageModes)
+	}
+	if !o.ComplexTables.valid() {
+		return fmt.Errorf("invalid complex tables mode %q: must be one of %s", o.ComplexTables, TableModes)
+	}
+	return nil
+}
+
 // ConvertToMarkdown converts HTML content to Markdown format
 func ConvertToMarkdown(html []byte) string {
+	return ConvertToMarkdownWithOptions(html, DefaultOptions())
+}
+
+// ConvertToMarkdownWithOptions converts HTML content to Markdown format using
+// the given conversion options
+func ConvertToMarkdownWithOptions(html []byte, opts *Options) string {
+	links := newLinkPlugin(opts.Links)
+	plugins := []converter.Plugin{
+		base.NewBasePlugin(),
+		commonmark.NewCommonmarkPlugin(),
+		links,
+		&imagePlugin{mode: opts.Images, download: opts.DownloadImage},
+		&tablePlugin{complex: opts.ComplexTables},
+		&codePlugin{},
+		&mathPlugin{},
+		&pagePlugin{},
+	}
+	if opts.NormalizeHeadings {
+		plugins = append(plugins, &headingPlugin{})
+	}
+
 	// Create a new converter with plugins
-	conv, err := htmltomarkdown.ConvertString(string(html))
+	conv := converter.NewConverter(converter.WithPlugins(plugins...))
 
+	markdown, err := conv.ConvertString(string(html))
 	if err != nil {
 		return string(html)
 	}
+	if opts.TOC {
+		markdown = insertTableOfContents(markdown)
+	}
 
-	return conv
+	return links.appendLinks(markdown)
 }
@@ -0,0 +1,211 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// Elements the formulas are replaced with before rendering, holding the TeX
+// in texAttr
+const (
+	inlineMathTag  = "md-fetch-math"
+	displayMathTag = "md-fetch-math-display"
+	texAttr        = "tex"
+)
+
+// mathPlugin renders MathJax, KaTeX and MathML formulas as LaTeX, $...$
+// inline and $$...$$ on their own lines
+type mathPlugin struct{}
+
+func (p *mathPlugin) Name() string {
+	return "md-fetch-math"
+}
+
+func (p *mathPlugin) Init(conv *converter.Converter) error {
+	// The TeX scripts of MathJax must be read before the base plugin
+	// removes every script
+	conv.Register.PreRenderer(p.replaceMath, converter.PriorityEarly-10)
+	conv.Register.RendererFor(inlineMathTag, converter.TagTypeInline, p.renderInline, converter.PriorityEarly)
+	conv.Register.RendererFor(displayMathTag, converter.TagTypeBlock, p.renderDisplay, converter.PriorityEarly)
+	return nil
+}
+
+func (p *mathPlugin) replaceMath(ctx converter.Context, doc *html.Node) {
+	// MathJax 2 keeps the source in a script after the rendered formula,
+	// which is dropped so the formula is not written twice
+	var scripts []*html.Node
+	walkElements(doc, func(n *html.Node) bool {
+		if isTeXScript(n) {
+			scripts = append(scripts, n)
+		}
+		return true
+	})
+	for _, script := range scripts {
+		for prev := script.PrevSibling; prev != nil && (isMathJaxOutput(prev) || isBlank(prev)); prev = script.PrevSibling {
+			prev.Parent.RemoveChild(prev)
+		}
+		_, mode, _ := strings.Cut(attr(script, "type"), ";")
+		display := strings.Contains(strings.ReplaceAll(mode, " ", ""), "mode=display")
+		replaceWithTeX(script, textContent(script), display)
+	}
+
+	var formulas []*html.Node
+	walkElements(doc, func(n *html.Node) bool {
+		if isFormula(n) {
+			formulas = append(formulas, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range formulas {
+		if tex := formulaTeX(n); tex != "" {
+			replaceWithTeX(n, tex, isDisplayFormula(n))
+		}
+	}
+}
+
+func (p *mathPlugin) renderInline(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	w.WriteString("$" + attr(n, texAttr) + "$")
+	return converter.RenderSuccess
+}
+
+func (p *mathPlugin) renderDisplay(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	w.WriteString("\n\n$$\n" + attr(n, texAttr) + "\n$$\n\n")
+	return converter.RenderSuccess
+}
+
+func isTeXScript(n *html.Node) bool {
+	t, _, _ := strings.Cut(attr(n, "type"), ";")
+	return n.Data == "script" && strings.EqualFold(strings.TrimSpace(t), "math/tex")
+}
+
+// isMathJaxOutput reports whether an element is MathJax 2 output or preview
+func isMathJaxOutput(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if strings.HasPrefix(class, "MathJax") {
+			return true
+		}
+	}
+	return false
+}
+
+// isFormula reports whether an element is a rendered formula: a MathML
+// <math>, or the wrapper KaTeX, MathJax 3 or Wikipedia put around it
+func isFormula(n *html.Node) bool {
+	if n.Data == "math" || n.Data == "mjx-container" {
+		return true
+	}
+	for _, class := range strings.Fields(attr(n, "class")) {
+		switch class {
+		case "katex", "katex-display", "mwe-math-element":
+			return true
+		}
+	}
+	return false
+}
+
+func isDisplayFormula(n *html.Node) bool {
+	if hasClass(n, "katex-display") || strings.EqualFold(attr(n, "display"), "true") || strings.EqualFold(attr(n, "display"), "block") {
+		return true
+	}
+	math := n
+	if n.Data != "math" {
+		math = findElement(n, func(n *html.Node) bool { return n.Data == "math" })
+	}
+	if math != nil && strings.EqualFold(attr(math, "display"), "block") {
+		return true
+	}
+	return findElement(n, func(n *html.Node) bool {
+		return hasClass(n, "mwe-math-fallback-image-display") || hasClass(n, "katex-display")
+	}) != nil
+}
+
+// formulaTeX returns the TeX of a formula: the source the page kept in a
+// TeX annotation or alt text, or else its MathML converted to TeX
+func formulaTeX(n *html.Node) string {
+	annotation := findElement(n, func(n *html.Node) bool {
+		encoding := strings.ToLower(attr(n, "encoding"))
+		return n.Data == "annotation" && (encoding == "application/x-tex" || encoding == "tex")
+	})
+	if annotation != nil {
+		return cleanTeX(textContent(annotation))
+	}
+
+	math := n
+	if n.Data != "math" {
+		math = findElement(n, func(n *html.Node) bool { return n.Data == "math" })
+	}
+	if math != nil {
+		if alt := attr(math, "alttext"); alt != "" {
+			return cleanTeX(alt)
+		}
+		return cleanTeX(mathMLToTeX(math))
+	}
+
+	// Wikipedia's image fallback carries the TeX in its alt text
+	if img := findElement(n, func(n *html.Node) bool { return n.Data == "img" }); img != nil {
+		return cleanTeX(attr(img, "alt"))
+	}
+	return ""
+}
+
+// cleanTeX trims a TeX source and the \displaystyle wrapper Wikipedia adds
+func cleanTeX(tex string) string {
+	tex = strings.TrimSpace(tex)
+	for _, style := range []string{`{\displaystyle `, `{\textstyle `} {
+		if strings.HasPrefix(tex, style) && strings.HasSuffix(tex, "}") {
+			tex = strings.TrimSpace(tex[len(style) : len(tex)-1])
+		}
+	}
+	return tex
+}
+
+func replaceWithTeX(n *html.Node, tex string, display bool) {
+	tag := inlineMathTag
+	if display {
+		tag = displayMathTag
+	}
+	// Line breaks would end the paragraph of an inline formula
+	if !display {
+		tex = strings.Join(strings.Fields(tex), " ")
+	}
+	math := &html.Node{
+		Type: html.ElementNode,
+		Data: tag,
+		Attr: []html.Attribute{{Key: texAttr, Val: strings.TrimSpace(tex)}},
+	}
+	// A text child keeps the element from being removed as empty
+	math.AppendChild(&html.Node{Type: html.TextNode, Data: "math"})
+	n.Parent.InsertBefore(math, n)
+	n.Parent.RemoveChild(n)
+}
+
+// walkElements calls fn for every element below n, descending into an
+// element when fn returns true
+func walkElements(n *html.Node, fn func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type != html.ElementNode || fn(c) {
+			walkElements(c, fn)
+		}
+		c = next
+	}
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlank(n *html.Node) bool {
+	return n.Type == html.TextNode && strings.TrimSpace(n.Data) == ""
+}
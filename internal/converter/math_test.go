@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"testing"
+)
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name:     "mathjax script",
+			html:     `<p>Energy <span class="MathJax_Preview">E=mc^2</span><span class="MathJax" id="MathJax-Element-1-Frame"><nobr>E=mc2</nobr><span class="MJX_Assistive_MathML"><math><mi>E</mi></math></span></span><script type="math/tex" id="MathJax-Element-1">E = mc^2</script> holds.</p>`,
+			expected: "Energy $E = mc^2$ holds.",
+		},
+		{
+			name:     "mathjax display script",
+			html:     `<p>Sum:</p><div class="MathJax_Display"><span class="MathJax">rendered</span></div><script type="math/tex; mode=display">\sum_{i=1}^n i = \frac{n(n+1)}{2}</script>`,
+			expected: "Sum:\n\n$$\n\\sum_{i=1}^n i = \\frac{n(n+1)}{2}\n$$",
+		},
+		{
+			name:     "katex annotation",
+			html:     `<p>Let <span class="katex"><span class="katex-mathml"><math><semantics><mrow><msup><mi>x</mi><mn>2</mn></msup></mrow><annotation encoding="application/x-tex">x^2 *_ y</annotation></semantics></math></span><span class="katex-html" aria-hidden="true">x2</span></span> be.</p>`,
+			expected: "Let $x^2 *_ y$ be.",
+		},
+		{
+			name:     "katex display",
+			html:     `<span class="katex-display"><span class="katex"><span class="katex-mathml"><math display="block"><semantics><mi>a</mi><annotation encoding="application/x-tex">a</annotation></semantics></math></span></span></span>`,
+			expected: "$$\na\n$$",
+		},
+		{
+			name:     "wikipedia",
+			html:     `<p>Area <span class="mwe-math-element"><span class="mwe-math-mathml-inline" style="display: none;"><math alttext="{\displaystyle \pi r^{2}}"><semantics><mrow><mi>π</mi></mrow><annotation encoding="application/x-tex">{\displaystyle \pi r^{2}}</annotation></semantics></math></span><img src="x.svg" class="mwe-math-fallback-image-inline" alt="{\displaystyle \pi r^{2}}"></span>.</p>`,
+			expected: "Area $\\pi r^{2}$.",
+		},
+		{
+			name:     "mathjax 3 mathml",
+			html:     `<p>Root <mjx-container class="MathJax" jax="CHTML"><mjx-math></mjx-math><mjx-assistive-mml><math><msqrt><mi>α</mi><mo>+</mo><mn>1</mn></msqrt></math></mjx-assistive-mml></mjx-container> here.</p>`,
+			expected: "Root $\\sqrt{\\alpha+1}$ here.",
+		},
+		{
+			name:     "mathml",
+			html:     `<math display="block"><mrow><munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>0</mn></mrow><mi>n</mi></munderover><mfrac><mn>1</mn><msup><mi>x</mi><mi>i</mi></msup></mfrac><mo>≤</mo><mi>sin</mi><mo>⁡</mo><mi>θ</mi></mrow></math>`,
+			expected: "$$\n\\sum_{i=0}^{n}\\frac{1}{x^{i}}\\leq\\sin\\theta\n$$",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ConvertToMarkdown([]byte(tt.html))
+			if result != tt.expected {
+				t.Errorf("\nexpected:\n%q\ngot:\n%q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// TeX commands of the symbols MathML writes as characters
+var texSymbols = map[rune]string{
+	'α': `\alpha`, 'β': `\beta`, 'γ': `\gamma`, 'δ': `\delta`, 'ε': `\epsilon`,
+	'ζ': `\zeta`, 'η': `\eta`, 'θ': `\theta`, 'ι': `\iota`, 'κ': `\kappa`,
+	'λ': `\lambda`, 'μ': `\mu`, 'ν': `\nu`, 'ξ': `\xi`, 'π': `\pi`,
+	'ρ': `\rho`, 'σ': `\sigma`, 'τ': `\tau`, 'υ': `\upsilon`, 'φ': `\phi`,
+	'χ': `\chi`, 'ψ': `\psi`, 'ω': `\omega`, 'ϕ': `\phi`, 'ϵ': `\epsilon`,
+	'Γ': `\Gamma`, 'Δ': `\Delta`, 'Θ': `\Theta`, 'Λ': `\Lambda`, 'Ξ': `\Xi`,
+	'Π': `\Pi`, 'Σ': `\Sigma`, 'Φ': `\Phi`, 'Ψ': `\Psi`, 'Ω': `\Omega`,
+	'×': `\times`, '÷': `\div`, '⋅': `\cdot`, '·': `\cdot`, '±': `\pm`,
+	'∓': `\mp`, '−': `-`, '≤': `\leq`, '≥': `\geq`, '≠': `\neq`,
+	'≈': `\approx`, '≡': `\equiv`, '∼': `\sim`, '∝': `\propto`, '→': `\to`,
+	'←': `\leftarrow`, '⇒': `\Rightarrow`, '⇔': `\Leftrightarrow`, '↦': `\mapsto`,
+	'∞': `\infty`, '∂': `\partial`, '∇': `\nabla`, '∑': `\sum`, '∏': `\prod`,
+	'∫': `\int`, '∮': `\oint`, '∈': `\in`, '∉': `\notin`, '⊂': `\subset`,
+	'⊆': `\subseteq`, '∪': `\cup`, '∩': `\cap`, '∅': `\emptyset`, '∀': `\forall`,
+	'∃': `\exists`, '¬': `\neg`, '∧': `\wedge`, '∨': `\vee`, '…': `\ldots`,
+	'⋯': `\cdots`, '′': `'`, '{': `\{`, '}': `\}`, '⟨': `\langle`, '⟩': `\rangle`,
+	'ℝ': `\mathbb{R}`, 'ℕ': `\mathbb{N}`, 'ℤ': `\mathbb{Z}`, 'ℚ': `\mathbb{Q}`, 'ℂ': `\mathbb{C}`,
+	'⁡': "", '⁢': "", '⁣': "", '⁤': "", // invisible operators
+}
+
+// Functions written upright with their own TeX command
+var texFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true,
+	"log": true, "ln": true, "exp": true, "lim": true, "max": true, "min": true,
+	"sup": true, "inf": true, "det": true, "dim": true, "gcd": true, "deg": true,
+}
+
+// TeX accents of the characters MathML puts over a base
+var texAccents = map[string]string{
+	"^": `\hat`, "ˆ": `\hat`, "~": `\tilde`, "˜": `\tilde`, "¯": `\bar`,
+	"‾": `\overline`, "→": `\vec`, "⃗": `\vec`, "˙": `\dot`, "¨": `\ddot`,
+}
+
+// mathMLToTeX converts a MathML formula to TeX. It covers the presentation
+// elements pages use in practice; anything else is written as its content.
+func mathMLToTeX(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return strings.TrimSpace(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	args := mathArgs(n)
+	switch n.Data {
+	case "mi":
+		return texIdentifier(strings.TrimSpace(textContent(n)))
+	case "mn":
+		return strings.TrimSpace(textContent(n))
+	case "mo":
+		return texOperator(strings.TrimSpace(textContent(n)))
+	case "mtext":
+		if text := textContent(n); strings.TrimSpace(text) != "" {
+			return `\text{` + text + `}`
+		}
+		return ""
+	case "mspace":
+		return `\ `
+	case "mphantom", "annotation", "annotation-xml":
+		return ""
+	case "semantics":
+		if len(args) > 0 {
+			return mathMLToTeX(args[0])
+		}
+		return ""
+	case "msup":
+		if len(args) == 2 {
+			return texBase(args[0]) + "^{" + mathMLToTeX(args[1]) + "}"
+		}
+	case "msub":
+		if len(args) == 2 {
+			return texBase(args[0]) + "_{" + mathMLToTeX(args[1]) + "}"
+		}
+	case "msubsup":
+		if len(args) == 3 {
+			return texBase(args[0]) + "_{" + mathMLToTeX(args[1]) + "}^{" + mathMLToTeX(args[2]) + "}"
+		}
+	case "mfrac":
+		if len(args) == 2 {
+			return `\frac{` + mathMLToTeX(args[0]) + "}{" + mathMLToTeX(args[1]) + "}"
+		}
+	case "msqrt":
+		return `\sqrt{` + texJoin(args) + "}"
+	case "mroot":
+		if len(args) == 2 {
+			return `\sqrt[` + mathMLToTeX(args[1]) + "]{" + mathMLToTeX(args[0]) + "}"
+		}
+	case "mover":
+		if len(args) == 2 {
+			base, over := mathMLToTeX(args[0]), strings.TrimSpace(textContent(args[1]))
+			if accent, ok := texAccents[over]; ok {
+				return accent + "{" + base + "}"
+			}
+			if isLargeOperator(args[0]) {
+				return base + "^{" + mathMLToTeX(args[1]) + "}"
+			}
+			return `\overset{` + mathMLToTeX(args[1]) + "}{" + base + "}"
+		}
+	case "munder":
+		if len(args) == 2 {
+			if isLargeOperator(args[0]) {
+				return mathMLToTeX(args[0]) + "_{" + mathMLToTeX(args[1]) + "}"
+			}
+			return `\underset{` + mathMLToTeX(args[1]) + "}{" + mathMLToTeX(args[0]) + "}"
+		}
+	case "munderover":
+		if len(args) == 3 {
+			if isLargeOperator(args[0]) {
+				return mathMLToTeX(args[0]) + "_{" + mathMLToTeX(args[1]) + "}^{" + mathMLToTeX(args[2]) + "}"
+			}
+			return `\underset{` + mathMLToTeX(args[1]) + `}{\overset{` + mathMLToTeX(args[2]) + "}{" + mathMLToTeX(args[0]) + "}}"
+		}
+	case "mfenced":
+		open, close := "(", ")"
+		if v, ok := attrValue(n, "open"); ok {
+			open = v
+		}
+		if v, ok := attrValue(n, "close"); ok {
+			close = v
+		}
+		separator := ","
+		if v, ok := attrValue(n, "separators"); ok {
+			separator = strings.TrimSpace(v)
+		}
+		parts := make([]string, len(args))
+		for i, arg := range args {
+			parts[i] = mathMLToTeX(arg)
+		}
+		return texDelimiter(open) + strings.Join(parts, separator) + texDelimiter(close)
+	case "mtable":
+		var rows []string
+		for _, row := range args {
+			var cells []string
+			for _, cell := range mathArgs(row) {
+				cells = append(cells, mathMLToTeX(cell))
+			}
+			rows = append(rows, strings.Join(cells, " & "))
+		}
+		return `\begin{matrix} ` + strings.Join(rows, ` \\ `) + ` \end{matrix}`
+	}
+	return texJoin(args)
+}
+
+// mathArgs returns the element children of a MathML element, its arguments
+func mathArgs(n *html.Node) []*html.Node {
+	var args []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			args = append(args, c)
+		}
+	}
+	return args
+}
+
+// texJoin writes the TeX of a row of elements, separating a command from a
+// following letter
+func texJoin(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		tex := mathMLToTeX(n)
+		if tex == "" {
+			continue
+		}
+		if endsWithCommand(b.String()) && startsWithLetter(tex) {
+			b.WriteString(" ")
+		}
+		b.WriteString(tex)
+	}
+	return b.String()
+}
+
+// texBase writes the base of a script, grouping it when it is more than a
+// single symbol
+func texBase(n *html.Node) string {
+	tex := mathMLToTeX(n)
+	if n.Data == "mrow" && len(mathArgs(n)) > 1 {
+		return "{" + tex + "}"
+	}
+	return tex
+}
+
+func texIdentifier(text string) string {
+	if texFunctions[text] {
+		return `\` + text
+	}
+	if len([]rune(text)) > 1 {
+		return `\mathrm{` + texText(text) + "}"
+	}
+	return texText(text)
+}
+
+func texOperator(text string) string {
+	if texFunctions[text] {
+		return `\` + text
+	}
+	return texText(text)
+}
+
+func texDelimiter(text string) string {
+	switch text {
+	case "{":
+		return `\{`
+	case "}":
+		return `\}`
+	}
+	return texText(text)
+}
+
+// texText writes text with its symbols as TeX commands
+func texText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if command, ok := texSymbols[r]; ok {
+			if endsWithCommand(b.String()) && startsWithLetter(command) {
+				b.WriteString(" ")
+			}
+			b.WriteString(command)
+			continue
+		}
+		if endsWithCommand(b.String()) && unicode.IsLetter(r) {
+			b.WriteString(" ")
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isLargeOperator(n *html.Node) bool {
+	switch strings.TrimSpace(textContent(n)) {
+	case "∑", "∏", "∫", "∮", "⋃", "⋂", "lim", "max", "min", "sup", "inf":
+		return true
+	}
+	return false
+}
+
+// endsWithCommand reports whether TeX ends with a command name, such as
+// \alpha, which a following letter would extend
+func endsWithCommand(tex string) bool {
+	i := len(tex)
+	for i > 0 && isASCIILetter(tex[i-1]) {
+		i--
+	}
+	return i < len(tex) && i > 0 && tex[i-1] == '\\'
+}
+
+func startsWithLetter(tex string) bool {
+	return tex != "" && isASCIILetter(tex[0])
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,479 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/net/html"
+)
+
+// Element the start of each page of a PDF document is marked with, holding
+// the page number in pageAttr
+const (
+	pageTag  = "md-fetch-page"
+	pageAttr = "page"
+)
+
+// pdfMagic opens every PDF file. Some servers send a few bytes before it,
+// which PDF readers tolerate, so it is looked for near the start.
+var pdfMagic = []byte("%PDF-")
+
+// IsPDF reports whether content is a PDF document: the magic comes first,
+// after at most a short prefix that holds no markup, so an HTML page quoting
+// it is not taken for a PDF
+func IsPDF(content []byte) bool {
+	i := bytes.Index(content[:min(len(content), 1024)], pdfMagic)
+	return i >= 0 && bytes.IndexByte(content[:i], '<') < 0
+}
+
+// PDFToHTML reconstructs the text of a PDF document as HTML, so it can be
+// converted like any page. Lines set larger than the body text, or bold,
+// become headings, and the other lines are gathered into paragraphs, bullet
+// lists and simple tables, each page opened by a page marker. The title and
+// author of the document become the title and author meta tag.
+func PDFToHTML(content []byte) (doc []byte, err error) {
+	// The parser panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	start := bytes.Index(content, pdfMagic)
+	if start < 0 {
+		return nil, errors.New("not a PDF document")
+	}
+	content = content[start:]
+	if bytes.HasPrefix(content, []byte("%PDF-2.")) {
+		// The parser only accepts PDF 1.x headers, but reads the text of
+		// PDF 2.0 files, which keep the same structure
+		content = append([]byte("%PDF-1.7"), content[len("%PDF-2.0"):]...)
+	}
+	r, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	var pages [][]*pdfLine
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		pages = append(pages, pageLines(page.Content().Text, newlineGlyphs(page)))
+	}
+
+	w := &pdfWriter{}
+	w.bodySize, w.headingSizes = fontSizes(pages)
+	for i, lines := range pages {
+		w.page(i+1, lines)
+	}
+	if !w.hasText {
+		return nil, errors.New("no text found in PDF, it may only hold scanned images")
+	}
+
+	info := r.Trailer().Key("Info")
+	title := strings.TrimSpace(info.Key("Title").Text())
+	if title == "" {
+		title = w.firstHeading
+	}
+	var b strings.Builder
+	b.WriteString("<html><head>")
+	if title != "" {
+		b.WriteString("<title>" + html.EscapeString(title) + "</title>")
+	}
+	if author := strings.TrimSpace(info.Key("Author").Text()); author != "" {
+		b.WriteString(`<meta name="author" content="` + html.EscapeString(author) + `">`)
+	}
+	b.WriteString("</head><body>\n" + w.body.String() + "</body></html>")
+	return []byte(b.String()), nil
+}
+
+// pdfLine is a line of text on a page. Text separated by a wide gap, as in
+// the columns of a table, is kept in separate cells.
+type pdfLine struct {
+	cells []string
+	x, y  float64 // Left edge and baseline
+	size  float64 // Largest font size
+	bold  bool
+}
+
+func (l *pdfLine) text() string {
+	return strings.Join(l.cells, " ")
+}
+
+// pdfSegment is a run of glyphs drawn one after the other on a line
+type pdfSegment struct {
+	text     strings.Builder
+	x, end   float64
+	lastX    float64 // Position of the last glyph
+	size     float64
+	bold     int // Number of bold glyphs, which make the line bold when most are
+	glyphs   int
+	baseline float64
+}
+
+// newlineGlyphs returns the text each font of a page reads a line break as.
+// The parser adds a line break after every TJ operator, which fonts with
+// their own encoding, such as those of TeX, read as a letter.
+func newlineGlyphs(page pdf.Page) map[string]string {
+	glyphs := map[string]string{}
+	for _, name := range page.Fonts() {
+		font := page.Font(name)
+		base := font.BaseFont()
+		if i := strings.Index(base, "+"); i >= 0 {
+			base = base[i+1:]
+		}
+		if s := font.Encoder().Decode("\n"); s != "\n" {
+			glyphs[base] = s
+		}
+	}
+	return glyphs
+}
+
+// pageLines gathers the glyphs of a page into lines, top to bottom,
+// skipping the line breaks added by the parser
+func pageLines(glyphs []pdf.Text, newlines map[string]string) []*pdfLine {
+	// Glyphs are grouped by baseline, then split into segments wherever
+	// the drawing jumps on the line
+	var rows [][]*pdfSegment
+	for _, g := range glyphs {
+		size := math.Abs(g.FontSize)
+		if size < 1 || g.S == "" || g.S == "\n" || newlines[g.Font] == g.S {
+			continue
+		}
+		var row *[]*pdfSegment
+		for i := range rows {
+			if math.Abs(rows[i][0].baseline-g.Y) < size*0.4 {
+				row = &rows[i]
+				break
+			}
+		}
+		if row == nil {
+			rows = append(rows, nil)
+			row = &rows[len(rows)-1]
+		}
+
+		seg := (*pdfSegment)(nil)
+		if n := len(*row); n > 0 {
+			last := (*row)[n-1]
+			if g.X >= last.lastX-size*0.1 && g.X-last.end < size*0.15 {
+				seg = last
+			}
+		}
+		if seg == nil {
+			seg = &pdfSegment{x: g.X, end: g.X, baseline: g.Y}
+			*row = append(*row, seg)
+		}
+		seg.text.WriteString(g.S)
+		seg.lastX = g.X
+		seg.size = max(seg.size, size)
+		if strings.TrimSpace(g.S) != "" {
+			seg.glyphs++
+			if isBoldFont(g.Font) {
+				seg.bold++
+			}
+		}
+		// Fonts without widths draw every glyph of a string at the same
+		// position, so their width is estimated
+		if g.W > 0 {
+			seg.end = g.X + g.W
+		} else {
+			seg.end = max(seg.end, g.X) + size*0.5*float64(utf8.RuneCountInString(g.S))
+		}
+	}
+
+	var lines []*pdfLine
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool { return row[i].x < row[j].x })
+		line := &pdfLine{x: row[0].x, y: row[0].baseline}
+		var cell strings.Builder
+		bold, count := 0, 0
+		end := math.Inf(-1)
+		for _, seg := range row {
+			text := strings.Join(strings.Fields(seg.text.String()), " ")
+			if text == "" {
+				continue
+			}
+			gap := seg.x - end
+			if gap > seg.size*1.5 && cell.Len() > 0 {
+				line.cells = append(line.cells, cell.String())
+				cell.Reset()
+			} else if cell.Len() > 0 && (gap > seg.size*0.15 || unicode.IsSpace(firstRune(seg.text.String()))) {
+				cell.WriteString(" ")
+			}
+			cell.WriteString(text)
+			end = seg.end
+			line.size = max(line.size, seg.size)
+			bold += seg.bold
+			count += seg.glyphs
+		}
+		if cell.Len() > 0 {
+			line.cells = append(line.cells, cell.String())
+		}
+		if len(line.cells) == 0 {
+			continue
+		}
+		line.bold = count > 0 && bold*2 > count
+		lines = append(lines, line)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].y > lines[j].y })
+
+	// Page numbers in the header or footer are not content
+	for len(lines) > 0 && pageNumber.MatchString(lines[len(lines)-1].text()) {
+		lines = lines[:len(lines)-1]
+	}
+	for len(lines) > 0 && pageNumber.MatchString(lines[0].text()) {
+		lines = lines[1:]
+	}
+	return lines
+}
+
+var pageNumber = regexp.MustCompile(`(?i)^(page\s+)?\d+(\s*(of|/)\s*\d+)?$|^[-–—]\s*\d+\s*[-–—]$`)
+
+func isBoldFont(font string) bool {
+	font = strings.ToLower(font)
+	return strings.Contains(font, "bold") || strings.Contains(font, "black") || strings.Contains(font, "heavy")
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// fontSizes returns the font size of the body text, the one most characters
+// are set in, and the larger sizes headings are set in, largest first
+func fontSizes(pages [][]*pdfLine) (float64, []float64) {
+	chars := map[float64]int{}
+	for _, lines := range pages {
+		for _, line := range lines {
+			chars[roundSize(line.size)] += utf8.RuneCountInString(line.text())
+		}
+	}
+	body := 0.0
+	for size, n := range chars {
+		if n > chars[body] || n == chars[body] && size < body {
+			body = size
+		}
+	}
+
+	var headings []float64
+	for size := range chars {
+		if size >= body*1.15 {
+			headings = append(headings, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(headings)))
+	// Beyond three levels, sizes are too close to tell sections apart
+	return body, headings[:min(len(headings), 3)]
+}
+
+func roundSize(size float64) float64 {
+	return math.Round(size*2) / 2
+}
+
+// Markers of list items: bullets, or numbers followed by a dot or a
+// parenthesis
+var (
+	bulletItem   = regexp.MustCompile(`^[•●▪■◦‣∙·○\-–*]\s+`)
+	numberedItem = regexp.MustCompile(`^\(?\d{1,3}[.)]\s+`)
+)
+
+// pdfWriter writes the lines of a document as HTML blocks
+type pdfWriter struct {
+	body         strings.Builder
+	bodySize     float64
+	headingSizes []float64
+	firstHeading string
+	hasText      bool
+
+	// The open block: a heading, paragraph, list or table
+	kind    string
+	level   int
+	ordered bool
+	lines   []string   // Lines of a heading or paragraph
+	items   [][]string // Lines of each list item
+	itemX   float64    // Left edge of the last list item
+	rows    [][]string // Cells of each table row
+}
+
+func (w *pdfWriter) page(number int, lines []*pdfLine) {
+	w.body.WriteString(fmt.Sprintf("<%s %s=\"%d\">Page %d</%s>\n", pageTag, pageAttr, number, number, pageTag))
+	var prev *pdfLine
+	for _, line := range lines {
+		w.hasText = true
+		// Lines further apart than their line spacing start a new block
+		brk := prev == nil || prev.y-line.y > max(prev.size, line.size)*1.6
+		prev = line
+		text := line.text()
+
+		if level := w.headingLevel(line); level > 0 {
+			if w.kind != "heading" || w.level != level || brk {
+				w.flush()
+				w.kind, w.level = "heading", level
+			}
+			w.lines = append(w.lines, text)
+			continue
+		}
+		if len(line.cells) > 1 {
+			if w.kind != "table" || len(w.rows[0]) != len(line.cells) {
+				w.flush()
+				w.kind = "table"
+			}
+			w.rows = append(w.rows, line.cells)
+			continue
+		}
+
+		marker := bulletItem.FindString(text)
+		ordered := false
+		if marker == "" {
+			marker = numberedItem.FindString(text)
+			ordered = marker != ""
+		}
+		switch {
+		case marker != "":
+			if w.kind != "list" || w.ordered != ordered {
+				w.flush()
+				w.kind, w.ordered = "list", ordered
+			}
+			w.items = append(w.items, []string{strings.TrimPrefix(text, marker)})
+			w.itemX = line.x
+		case w.kind == "list" && !brk && line.x > w.itemX+1:
+			// Indented lines continue the item
+			item := &w.items[len(w.items)-1]
+			*item = append(*item, text)
+		case w.kind == "paragraph" && !brk:
+			w.lines = append(w.lines, text)
+		default:
+			w.flush()
+			w.kind = "paragraph"
+			w.lines = []string{text}
+		}
+	}
+	// Blocks end with the page, as the page marker separates them
+	w.flush()
+}
+
+// headingLevel returns the heading level of a line, or 0 for body text
+func (w *pdfWriter) headingLevel(line *pdfLine) int {
+	text := line.text()
+	if len(line.cells) > 1 || utf8.RuneCountInString(text) > 120 || !strings.ContainsFunc(text, unicode.IsLetter) {
+		return 0
+	}
+	for i, size := range w.headingSizes {
+		if roundSize(line.size) == size {
+			return i + 1
+		}
+	}
+	// Short bold lines set in the body size are the lowest headings
+	if line.bold && roundSize(line.size) >= w.bodySize && utf8.RuneCountInString(text) <= 80 &&
+		!strings.ContainsAny(text[len(text)-1:], ".,;:") {
+		return len(w.headingSizes) + 1
+	}
+	return 0
+}
+
+// flush writes the open block
+func (w *pdfWriter) flush() {
+	switch w.kind {
+	case "heading":
+		text := joinLines(w.lines)
+		if w.firstHeading == "" {
+			w.firstHeading = text
+		}
+		w.body.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", w.level, html.EscapeString(text), w.level))
+	case "paragraph":
+		w.body.WriteString("<p>" + html.EscapeString(joinLines(w.lines)) + "</p>\n")
+	case "list":
+		tag := "ul"
+		if w.ordered {
+			tag = "ol"
+		}
+		w.body.WriteString("<" + tag + ">\n")
+		for _, item := range w.items {
+			w.body.WriteString("<li>" + html.EscapeString(joinLines(item)) + "</li>\n")
+		}
+		w.body.WriteString("</" + tag + ">\n")
+	case "table":
+		if len(w.rows) == 1 {
+			// A single row is text with wide gaps, not a table
+			w.body.WriteString("<p>" + html.EscapeString(strings.Join(w.rows[0], " ")) + "</p>\n")
+			break
+		}
+		w.body.WriteString("<table>\n")
+		for i, row := range w.rows {
+			cell := "td"
+			if i == 0 {
+				cell = "th"
+			}
+			w.body.WriteString("<tr>")
+			for _, text := range row {
+				w.body.WriteString("<" + cell + ">" + html.EscapeString(text) + "</" + cell + ">")
+			}
+			w.body.WriteString("</tr>\n")
+		}
+		w.body.WriteString("</table>\n")
+	}
+	w.kind, w.lines, w.items, w.rows = "", nil, nil, nil
+}
+
+// joinLines joins the lines of a block, rejoining words hyphenated at the
+// end of a line
+func joinLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			if hyphenated(prev) && unicode.IsLower(firstRune(line)) {
+				s := b.String()
+				b.Reset()
+				b.WriteString(s[:len(s)-1])
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func hyphenated(line string) bool {
+	if !strings.HasSuffix(line, "-") || len(line) < 2 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(line[:len(line)-1])
+	return unicode.IsLetter(r)
+}
+
+// pagePlugin writes the page markers of PDF documents as HTML comments
+type pagePlugin struct{}
+
+func (p *pagePlugin) Name() string {
+	return "md-fetch-page"
+}
+
+func (p *pagePlugin) Init(conv *converter.Converter) error {
+	conv.Register.RendererFor(pageTag, converter.TagTypeBlock, p.renderPage, converter.PriorityEarly)
+	return nil
+}
+
+func (p *pagePlugin) renderPage(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	w.WriteString("\n\n<!-- Page " + attr(n, pageAttr) + " -->\n\n")
+	return converter.RenderSuccess
+}
+
+// pageNumberOf returns the page number of a page marker
+func pageNumberOf(n *html.Node) int {
+	number, _ := strconv.Atoi(attr(n, pageAttr))
+	return number
+}
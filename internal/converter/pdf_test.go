@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a PDF document with a page for each content stream, set
+// in Helvetica as /F1 and Helvetica-Bold as /F2
+func buildPDF(info string, pages ...string) []byte {
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", ""}
+	var kids []string
+	for _, content := range pages {
+		page := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+				page+1, 3+2*len(pages), 4+2*len(pages)),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content)+1, content))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+	objects = append(objects,
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>",
+		"<< "+info+" >>")
+
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return []byte(b.String())
+}
+
+// text draws a line of text at x, y
+func text(font string, size, x, y int, s string) string {
+	return fmt.Sprintf("BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, size, x, y, s)
+}
+
+func TestPDFToHTML(t *testing.T) {
+	document := buildPDF("/Title (Quarterly Report) /Author (Ada Lovelace)",
+		text("F1", 24, 72, 720, "Quarterly Report")+
+			text("F1", 11, 72, 690, "Sales grew in every re-")+
+			text("F1", 11, 72, 676, "gion this quarter.")+
+			text("F2", 11, 72, 650, "Highlights")+
+			text("F1", 11, 72, 630, "- New stores opened")+
+			text("F1", 11, 72, 616, "- Costs fell")+
+			text("F1", 11, 72, 590, "Region")+text("F1", 11, 250, 590, "Sales")+
+			text("F1", 11, 72, 576, "North")+text("F1", 11, 250, 576, "120")+
+			text("F1", 11, 72, 562, "South")+text("F1", 11, 250, 562, "95")+
+			text("F1", 9, 300, 40, "1"),
+		text("F1", 16, 72, 720, "Outlook")+
+			text("F1", 11, 72, 700, "Growth continues.")+
+			text("F1", 9, 300, 40, "2"))
+
+	if !IsPDF(document) || !IsPDF(append([]byte("\r\n\x00junk"), document...)) {
+		t.Fatal("expected the document to be recognized as a PDF")
+	}
+	if IsPDF([]byte("<html></html>")) || IsPDF([]byte("<html><body><p>Saved as %PDF-1.7</p></body></html>")) {
+		t.Fatal("expected HTML pages not to be recognized as a PDF")
+	}
+	content, err := PDFToHTML(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `<title>Quarterly Report</title><meta name="author" content="Ada Lovelace">`) {
+		t.Errorf("expected the document info in the head, got:\n%s", content)
+	}
+
+	expected := "<!-- Page 1 -->\n\n" +
+		"# Quarterly Report\n\n" +
+		"Sales grew in every region this quarter.\n\n" +
+		"### Highlights\n\n" +
+		"- New stores opened\n- Costs fell\n\n" +
+		"| Region | Sales |\n| --- | --- |\n| North | 120 |\n| South | 95 |\n\n" +
+		"<!-- Page 2 -->\n\n" +
+		"## Outlook\n\n" +
+		"Growth continues."
+	if result := ConvertToMarkdown(content); result != expected {
+		t.Errorf("\nexpected:\n%s\ngot:\n%s", expected, result)
+	}
+
+	if result := ConvertToText(content); !strings.HasPrefix(result, "[Page 1]\n\nQuarterly Report") {
+		t.Errorf("expected a page marker in the text, got:\n%s", result)
+	}
+}
+
+func TestPDFToHTMLWithoutText(t *testing.T) {
+	if _, err := PDFToHTML(buildPDF("", "0 0 m 100 100 l S")); err == nil {
+		t.Error("expected an error for a PDF without text")
+	}
+	if _, err := PDFToHTML([]byte("%PDF-1.4\ngarbage")); err == nil {
+		t.Error("expected an error for a malformed PDF")
+	}
+}
@@ -0,0 +1,516 @@
+package converter
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/marker"
+	"golang.org/x/net/html"
+)
+
+// TableMode selects how tables that cannot be written as pipe tables, those
+// with merged cells, nested tables or block content, are rendered
+type TableMode string
+
+const (
+	TableHTML    TableMode = "html"    // cleaned HTML keeping only the table structure
+	TableFlatten TableMode = "flatten" // one list item per row, each value labelled with its column header
+)
+
+// TableModes lists the supported complex table modes
+var TableModes = []TableMode{TableHTML, TableFlatten}
+
+func (m TableMode) valid() bool {
+	if m == "" {
+		return true
+	}
+	for _, mode := range TableModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Elements that make a cell too complex for a pipe table
+var blockElements = map[string]bool{
+	"address":    true,
+	"article":    true,
+	"blockquote": true,
+	"dl":         true,
+	"fieldset":   true,
+	"figure":     true,
+	"form":       true,
+	"h1":         true,
+	"h2":         true,
+	"h3":         true,
+	"h4":         true,
+	"h5":         true,
+	"h6":         true,
+	"hr":         true,
+	"ol":         true,
+	"pre":        true,
+	"section":    true,
+	"table":      true,
+	"ul":         true,
+}
+
+// Attributes kept when a table is written as HTML
+var tableAttrs = map[string]bool{
+	"colspan": true,
+	"rowspan": true,
+	"scope":   true,
+	"headers": true,
+}
+
+// tablePlugin renders data tables as GFM pipe tables, complex tables in the
+// configured fallback and layout tables as plain blocks
+type tablePlugin struct {
+	complex TableMode
+}
+
+func (p *tablePlugin) Name() string {
+	return "md-fetch-tables"
+}
+
+func (p *tablePlugin) Init(conv *converter.Converter) error {
+	conv.Register.RendererFor("table", converter.TagTypeBlock, p.renderTable, converter.PriorityEarly)
+	return nil
+}
+
+// table is the parsed structure of a table element
+type table struct {
+	caption *html.Node
+	rows    [][]*html.Node // cells of each row, th and td
+	header  int            // number of leading header rows
+	columns int
+	spans   bool // some cell spans several rows or columns
+	nested  bool // some cell contains another table
+	blocks  bool // some cell contains block content
+	wrapped bool // some cell wraps its content in a paragraph or div
+}
+
+func parseTable(n *html.Node) *table {
+	t := &table{}
+	headerDone := false
+	var visit func(*html.Node, bool)
+	visit = func(n *html.Node, inHead bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "caption":
+				if t.caption == nil {
+					t.caption = c
+				}
+			case "thead":
+				visit(c, true)
+			case "tbody", "tfoot":
+				visit(c, false)
+			case "tr":
+				row := t.parseRow(c)
+				if len(row) == 0 {
+					continue
+				}
+				// Header rows lead the table and are in <thead> or made of
+				// <th> cells only
+				if !headerDone && (inHead || allHeaderCells(row)) {
+					t.header++
+				} else {
+					headerDone = true
+				}
+				t.rows = append(t.rows, row)
+			}
+		}
+	}
+	visit(n, false)
+	return t
+}
+
+func (t *table) parseRow(tr *html.Node) []*html.Node {
+	var cells []*html.Node
+	width := 0
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || (c.Data != "td" && c.Data != "th") {
+			continue
+		}
+		cells = append(cells, c)
+		colspan, rowspan := span(c, "colspan"), span(c, "rowspan")
+		if colspan > 1 || rowspan > 1 {
+			t.spans = true
+		}
+		width += colspan
+		t.inspectCell(c)
+	}
+	if width > t.columns {
+		t.columns = width
+	}
+	return cells
+}
+
+// inspectCell records nested tables and block content in a cell
+func (t *table) inspectCell(cell *html.Node) {
+	paragraphs := 0
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch {
+			case c.Data == "table":
+				t.nested = true
+			case blockElements[c.Data]:
+				t.blocks = true
+			case c.Data == "p" || c.Data == "div":
+				paragraphs++
+				t.wrapped = true
+			}
+			visit(c)
+		}
+	}
+	visit(cell)
+	if paragraphs > 1 {
+		t.blocks = true
+	}
+}
+
+// isLayout reports whether a table only positions content on the page,
+// following the heuristics browsers use for accessibility
+func (t *table) isLayout(n *html.Node) bool {
+	switch strings.ToLower(attr(n, "role")) {
+	case "presentation", "none":
+		return true
+	case "table", "grid", "treegrid":
+		return false
+	}
+	if t.caption != nil || t.header > 0 || attr(n, "summary") != "" || hasChild(n, "thead", "tfoot", "colgroup") {
+		return false
+	}
+	for _, row := range t.rows {
+		for _, cell := range row {
+			if cell.Data == "th" {
+				return false
+			}
+		}
+	}
+	// Small tables without headers are often key/value data, such as the
+	// specifications of a product, so only cells holding tables or blocks of
+	// content, and a lone cell framing its content, are taken for layout
+	if t.nested || t.blocks || t.wrapped {
+		return true
+	}
+	return len(t.rows) == 1 && t.columns <= 1
+}
+
+// Bounds on the grid of a flattened table. Spans are capped per cell, but a
+// row of many wide cells, or many rows, would still make the grid huge, so
+// larger tables are left as HTML.
+const (
+	maxGridColumns = 1000
+	maxGridSlots   = 100000
+)
+
+func (p *tablePlugin) renderTable(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	t := parseTable(n)
+	if len(t.rows) == 0 {
+		return converter.RenderSuccess
+	}
+
+	var out []byte
+	caption := t.caption != nil
+	switch {
+	case t.isLayout(n):
+		out = t.renderLayout(ctx)
+	case t.spans || t.nested || t.blocks:
+		if p.complex == TableFlatten && t.columns <= maxGridColumns && len(t.rows)*t.columns <= maxGridSlots {
+			out = t.renderFlattened(ctx)
+		} else {
+			// The HTML keeps its own <caption>
+			out = t.renderHTML(n)
+			caption = false
+		}
+	default:
+		out = t.renderPipe(ctx)
+	}
+
+	if caption {
+		if caption := inlineContent(ctx, t.caption); caption != "" {
+			out = append([]byte(caption+"\n\n"), out...)
+		}
+	}
+
+	w.WriteString("\n\n")
+	w.Write(out)
+	w.WriteString("\n\n")
+	return converter.RenderSuccess
+}
+
+// renderLayout writes the content of every cell as its own block
+func (t *table) renderLayout(ctx converter.Context) []byte {
+	var blocks [][]byte
+	for _, row := range t.rows {
+		for _, cell := range row {
+			var buf bytes.Buffer
+			ctx.RenderChildNodes(ctx, &buf, cell)
+			if content := bytes.TrimSpace(buf.Bytes()); len(content) > 0 {
+				blocks = append(blocks, content)
+			}
+		}
+	}
+	return bytes.Join(blocks, []byte("\n\n"))
+}
+
+// renderPipe writes a GFM pipe table. Tables without a header row get an
+// empty one, as GFM requires it.
+func (t *table) renderPipe(ctx converter.Context) []byte {
+	var b bytes.Buffer
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i := 0; i < t.columns; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	body := t.rows
+	var header []string
+	if t.header > 0 {
+		header = t.cellTexts(ctx, t.rows[0])
+		body = t.rows[1:]
+	}
+	writeRow(header)
+
+	b.WriteString("|")
+	for i := 0; i < t.columns; i++ {
+		b.WriteString(" " + t.columnAlignment(i) + " |")
+	}
+	b.WriteString("\n")
+
+	for _, row := range body {
+		writeRow(t.cellTexts(ctx, row))
+	}
+	return bytes.TrimRight(b.Bytes(), "\n")
+}
+
+// renderFlattened writes one list item per body row, labelling each value
+// with its column header. A cell spanning several rows is repeated in each of
+// them, one spanning several columns is written once under its first header.
+func (t *table) renderFlattened(ctx converter.Context) []byte {
+	grid := t.grid(ctx)
+
+	headers := make([]string, t.columns)
+	for _, row := range grid[:t.header] {
+		for i, slot := range row {
+			if slot.text == "" || (i > 0 && row[i-1].cell == slot.cell) {
+				continue
+			}
+			headers[i] = strings.TrimSpace(headers[i] + " " + slot.text)
+		}
+	}
+
+	var b bytes.Buffer
+	for _, row := range grid[t.header:] {
+		var fields []string
+		for i, slot := range row {
+			if slot.text == "" || (i > 0 && row[i-1].cell == slot.cell) {
+				continue
+			}
+			field := slot.text
+			if headers[i] != "" {
+				field = headers[i] + ": " + field
+			}
+			fields = append(fields, field)
+		}
+		if len(fields) > 0 {
+			b.WriteString("- " + strings.Join(fields, "; ") + "\n")
+		}
+	}
+	return bytes.TrimRight(b.Bytes(), "\n")
+}
+
+// slot is a position of the table grid and the cell covering it
+type slot struct {
+	cell *html.Node
+	text string
+}
+
+// grid lays the cells out on a grid of rows by columns, expanding merged
+// cells over every position they cover
+func (t *table) grid(ctx converter.Context) [][]slot {
+	grid := make([][]slot, len(t.rows))
+	for r := range grid {
+		grid[r] = make([]slot, t.columns)
+	}
+	for r, row := range t.rows {
+		col := 0
+		for _, cell := range row {
+			for col < t.columns && grid[r][col].cell != nil {
+				col++
+			}
+			text := strings.ReplaceAll(inlineContent(ctx, cell), "<br>", " ")
+			colspan := span(cell, "colspan")
+			for dr := 0; dr < span(cell, "rowspan") && r+dr < len(grid); dr++ {
+				for dc := 0; dc < colspan && col+dc < t.columns; dc++ {
+					grid[r+dr][col+dc] = slot{cell: cell, text: text}
+				}
+			}
+			col += colspan
+		}
+	}
+	return grid
+}
+
+// renderHTML writes the table as HTML, keeping only the attributes that
+// describe its structure
+func (t *table) renderHTML(n *html.Node) []byte {
+	clone := cloneNode(n)
+	var strip func(*html.Node)
+	strip = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			var kept []html.Attribute
+			for _, a := range n.Attr {
+				if tableAttrs[a.Key] || (n.Data == "a" && a.Key == "href") || (n.Data == "img" && (a.Key == "src" || a.Key == "alt")) {
+					kept = append(kept, a)
+				}
+			}
+			n.Attr = kept
+		}
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			// Whitespace between rows and cells only adds tokens
+			if c.Type == html.TextNode && strings.TrimSpace(c.Data) == "" && n.Type == html.ElementNode && isTableStructure(n.Data) {
+				n.RemoveChild(c)
+			} else {
+				strip(c)
+			}
+			c = next
+		}
+	}
+	strip(clone)
+
+	var buf bytes.Buffer
+	html.Render(&buf, clone)
+	return buf.Bytes()
+}
+
+func (t *table) cellTexts(ctx converter.Context, row []*html.Node) []string {
+	texts := make([]string, len(row))
+	for i, cell := range row {
+		// Pipes end the cell even inside code spans, so they are always
+		// escaped, replacing the converter's own escape marker
+		text := strings.ReplaceAll(inlineContent(ctx, cell), string(marker.MarkerEscaping)+"|", "|")
+		texts[i] = strings.ReplaceAll(text, "|", `\|`)
+	}
+	return texts
+}
+
+// columnAlignment returns the delimiter of a column, aligned like its
+// header cell or else its first body cell
+func (t *table) columnAlignment(i int) string {
+	for _, row := range t.rows {
+		if i < len(row) {
+			if align := cellAlignment(row[i]); align != "" {
+				return align
+			}
+		}
+	}
+	return "---"
+}
+
+func cellAlignment(cell *html.Node) string {
+	align := strings.ToLower(attr(cell, "align"))
+	for _, decl := range strings.Split(attr(cell, "style"), ";") {
+		if name, value, ok := strings.Cut(decl, ":"); ok && strings.TrimSpace(strings.ToLower(name)) == "text-align" {
+			align = strings.TrimSpace(strings.ToLower(value))
+		}
+	}
+	switch align {
+	case "left":
+		return ":---"
+	case "center":
+		return ":---:"
+	case "right":
+		return "---:"
+	}
+	return ""
+}
+
+// inlineContent renders an element's children as a single line of Markdown,
+// with line breaks written as <br>
+func inlineContent(ctx converter.Context, n *html.Node) string {
+	var buf bytes.Buffer
+	ctx.RenderChildNodes(ctx, &buf, n)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var kept []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "<br>")
+}
+
+func allHeaderCells(row []*html.Node) bool {
+	for _, cell := range row {
+		if cell.Data != "th" {
+			return false
+		}
+	}
+	return true
+}
+
+func span(cell *html.Node, key string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(attr(cell, key)))
+	if err != nil || n < 1 {
+		return 1
+	}
+	// Browsers cap spans, which also keeps hostile markup from blowing up
+	// the flattened grid
+	if n > 1000 {
+		return 1000
+	}
+	return n
+}
+
+func hasChild(n *html.Node, tags ...string) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			for _, tag := range tags {
+				if c.Data == tag {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isTableStructure(tag string) bool {
+	switch tag {
+	case "table", "thead", "tbody", "tfoot", "tr", "colgroup":
+		return true
+	}
+	return false
+}
+
+func cloneNode(n *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		clone.AppendChild(cloneNode(c))
+	}
+	return clone
+}
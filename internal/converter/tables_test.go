@@ -0,0 +1,99 @@
+package converter
+
+import (
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		mode     TableMode
+		expected string
+	}{
+		{
+			name: "header row",
+			html: `<table>
+				<caption>Releases</caption>
+				<thead><tr><th>Version</th><th align="right">Size</th></tr></thead>
+				<tbody>
+					<tr><td><code>v1.0</code></td><td>12 MB</td></tr>
+					<tr><td>v1.1</td><td>a | b</td></tr>
+					<tr><td>v2.0</td><td>15<br>MB</td></tr>
+					<tr><td>v2.1</td><td></td></tr>
+				</tbody>
+			</table>`,
+			expected: "Releases\n\n| Version | Size |\n| --- | ---: |\n| `v1.0` | 12 MB |\n| v1.1 | a \\| b |\n| v2.0 | 15<br>MB |\n| v2.1 |  |",
+		},
+		{
+			name:     "header cells in first row",
+			html:     `<table><tr><th>Key</th><th>Value</th></tr><tr><td>a</td><td>1</td></tr></table>`,
+			expected: "| Key | Value |\n| --- | --- |\n| a | 1 |",
+		},
+		{
+			name: "no header",
+			html: `<table>
+				<tr><td>1</td><td>2</td><td>3</td><td>4</td><td>5</td></tr>
+				<tr><td>6</td><td>7</td><td>8</td><td>9</td><td>10</td></tr>
+			</table>`,
+			expected: "|  |  |  |  |  |\n| --- | --- | --- | --- | --- |\n| 1 | 2 | 3 | 4 | 5 |\n| 6 | 7 | 8 | 9 | 10 |",
+		},
+		{
+			name:     "layout table",
+			html:     `<table><tr><td><p>Main <b>text</b></p></td><td>Sidebar</td></tr></table>`,
+			expected: "Main **text**\n\nSidebar",
+		},
+		{
+			name:     "key value table",
+			html:     `<table><tr><td>Weight</td><td>1.2 kg</td></tr><tr><td>Color</td><td>Red</td></tr><tr><td>Size</td><td>XL</td></tr></table>`,
+			expected: "|  |  |\n| --- | --- |\n| Weight | 1.2 kg |\n| Color | Red |\n| Size | XL |",
+		},
+		{
+			name:     "single cell",
+			html:     `<table><tr><td>Framed <b>text</b></td></tr></table>`,
+			expected: "Framed **text**",
+		},
+		{
+			name:     "presentation role",
+			html:     `<table role="presentation"><tr><th>A</th></tr><tr><td>B</td></tr></table>`,
+			expected: "A\n\nB",
+		},
+		{
+			name:     "merged cells as html",
+			html:     `<table class="data"><tr><th style="color: red">A</th><th>B</th></tr><tr><td colspan="2"><a href="https://example.com" class="x">wide</a></td></tr></table>`,
+			expected: `<table><tbody><tr><th>A</th><th>B</th></tr><tr><td colspan="2"><a href="https://example.com">wide</a></td></tr></tbody></table>`,
+		},
+		{
+			name: "merged cells flattened",
+			html: `<table>
+				<caption>Plans</caption>
+				<tr><th>Plan</th><th>Price</th><th>Support</th></tr>
+				<tr><td rowspan="2">Team</td><td>$10</td><td>Email</td></tr>
+				<tr><td colspan="2">Contact us</td></tr>
+			</table>`,
+			mode:     TableFlatten,
+			expected: "Plans\n\n- Plan: Team; Price: $10; Support: Email\n- Plan: Team; Price: Contact us",
+		},
+		{
+			name:     "block content flattened",
+			html:     `<table><tr><th>Step</th><th>Notes</th></tr><tr><td>1</td><td><ul><li>one</li><li>two</li></ul></td></tr></table>`,
+			mode:     TableFlatten,
+			expected: "- Step: 1; Notes: - one - two",
+		},
+		{
+			name:     "table too wide to flatten",
+			html:     `<table><tr><th>A</th></tr><tr><td colspan="1000">x</td><td colspan="1000">y</td></tr></table>`,
+			mode:     TableFlatten,
+			expected: `<table><tbody><tr><th>A</th></tr><tr><td colspan="1000">x</td><td colspan="1000">y</td></tr></tbody></table>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ConvertToMarkdownWithOptions([]byte(tt.html), &Options{ComplexTables: tt.mode})
+			if result != tt.expected {
+				t.Errorf("\nexpected:\n%q\ngot:\n%q", tt.expected, result)
+			}
+		})
+	}
+}
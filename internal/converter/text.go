@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ConvertToText converts HTML content to plain prose, keeping its
+// paragraphs, lists and tables but none of their markup
+func ConvertToText(content []byte) string {
+	return strings.TrimSpace(textBlocks(ExtractBlocks(content)))
+}
+
+func textBlocks(blocks []*Block) string {
+	var parts []string
+	for _, block := range blocks {
+		if text := textBlock(block); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func textBlock(block *Block) string {
+	switch block.Type {
+	case BlockList:
+		items := make([]string, len(block.Children))
+		for i, item := range block.Children {
+			marker := "- "
+			if block.Ordered {
+				marker = strconv.Itoa(i+1) + ". "
+			}
+			text := item.Text
+			if nested := textBlocks(item.Children); nested != "" {
+				text = strings.TrimLeft(text+"\n"+nested, "\n")
+			}
+			items[i] = marker + indentLines(text, strings.Repeat(" ", len(marker)))
+		}
+		return strings.Join(items, "\n")
+	case BlockQuote:
+		return "  " + indentLines(textBlocks(block.Children), "  ")
+	case BlockTable:
+		rows := make([]string, len(block.Rows))
+		for i, row := range block.Rows {
+			rows[i] = strings.Join(row, "\t")
+		}
+		if block.Text != "" {
+			rows = append([]string{block.Text}, rows...)
+		}
+		return strings.Join(rows, "\n")
+	case BlockImage:
+		return block.Alt
+	case BlockRule:
+		return ""
+	case BlockPage:
+		return "[Page " + strconv.Itoa(block.Page) + "]"
+	}
+	return block.Text
+}
+
+// indentLines indents every line of text but the first
+func indentLines(text, indent string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+indent)
+}
@@ -0,0 +1,460 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// SummaryLength is the number of characters entry summaries are shortened
+// to in a digest
+const SummaryLength = 300
+
+// Feed is an RSS or Atom feed
+type Feed struct {
+	Title       string
+	Link        string // The site the feed belongs to
+	Description string
+	Entries     []*Entry
+}
+
+// Entry is an item of an RSS feed or an entry of an Atom feed
+type Entry struct {
+	Title     string
+	Link      string
+	Published string // Publication date as YYYY-MM-DD, or as written when it cannot be read
+	Author    string
+	Summary   string // HTML summary, or the content when there is none
+
+	// Article is the HTML of the full article the entry links to, which
+	// the digest shows instead of the summary. Err reports why it could
+	// not be fetched.
+	Article []byte
+	Err     error
+}
+
+// IsFeed reports whether content is an RSS 0.9x, 1.0 or 2.0 or an Atom feed,
+// by the root element of the XML document
+func IsFeed(content []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(content))
+	d.Strict = false
+	d.CharsetReader = charset.NewReaderLabel
+	// The root follows at most a declaration, a doctype, comments and
+	// processing instructions such as stylesheets
+	for i := 0; i < 20; i++ {
+		token, err := d.RawToken()
+		if err != nil {
+			return false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t.Name.Local == "rss" || t.Name.Local == "feed" || t.Name.Local == "RDF"
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// Parse reads an RSS or Atom feed loaded from feedURL, against which
+// relative links are resolved
+func Parse(content []byte, feedURL string) (*Feed, error) {
+	var doc struct {
+		XMLName xml.Name
+
+		// RSS
+		Channel rssChannel `xml:"channel"`
+		Items   []rssItem  `xml:"item"` // RSS 1.0 items are siblings of the channel
+
+		// Atom
+		Title    atomText    `xml:"title"`
+		Subtitle atomText    `xml:"subtitle"`
+		Links    []atomLink  `xml:"link"`
+		Entries  []atomEntry `xml:"entry"`
+	}
+	d := xml.NewDecoder(bytes.NewReader(content))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charset.NewReaderLabel
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	f := &Feed{}
+	switch doc.XMLName.Local {
+	case "rss", "RDF":
+		f.Title = collapse(doc.Channel.Title)
+		f.Link = resolve(feedURL, rssLink(doc.Channel.Links))
+		f.Description = collapse(doc.Channel.Description)
+		for _, item := range append(doc.Channel.Items, doc.Items...) {
+			f.Entries = append(f.Entries, item.entry(feedURL))
+		}
+	case "feed":
+		f.Title = collapse(doc.Title.plain())
+		f.Link = resolve(feedURL, atomHref(doc.Links))
+		f.Description = collapse(doc.Subtitle.plain())
+		for _, entry := range doc.Entries {
+			f.Entries = append(f.Entries, entry.entry(feedURL))
+		}
+	default:
+		return nil, errors.New("not an RSS or Atom feed")
+	}
+	return f, nil
+}
+
+type rssChannel struct {
+	Title       string      `xml:"title"`
+	Links       []namedLink `xml:"link"`
+	Description string      `xml:"description"`
+	Items       []rssItem   `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string      `xml:"title"`
+	Links       []namedLink `xml:"link"`
+	GUID        rssGUID     `xml:"guid"`
+	Description string      `xml:"description"`
+	Content     string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string      `xml:"pubDate"`
+	Date        string      `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string      `xml:"author"`
+	Creator     string      `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// namedLink is an RSS <link>, or an <atom:link> some RSS feeds add
+type namedLink struct {
+	XMLName xml.Name
+	Href    string `xml:"href,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Text        string `xml:",chardata"`
+}
+
+func (item *rssItem) entry(feedURL string) *Entry {
+	link := rssLink(item.Links)
+	if link == "" && item.GUID.IsPermaLink != "false" && strings.HasPrefix(strings.TrimSpace(item.GUID.Text), "http") {
+		// A GUID is the permalink unless marked otherwise
+		link = strings.TrimSpace(item.GUID.Text)
+	}
+	return &Entry{
+		Title:     collapse(item.Title),
+		Link:      resolve(feedURL, link),
+		Published: formatDate(first(item.PubDate, item.Date)),
+		Author:    collapse(first(item.Creator, item.Author)),
+		Summary:   strings.TrimSpace(first(item.Description, item.Content)),
+	}
+}
+
+// rssLink returns the text of the first RSS <link>
+func rssLink(links []namedLink) string {
+	for _, link := range links {
+		if text := strings.TrimSpace(link.Text); text != "" {
+			return text
+		}
+	}
+	for _, link := range links {
+		if href := strings.TrimSpace(link.Href); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+type atomEntry struct {
+	Title     atomText   `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	ID        string     `xml:"id"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Summary atomText `xml:"summary"`
+	Content atomText `xml:"content"`
+}
+
+// atomText is an Atom text construct, holding text, escaped HTML or XHTML
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the text construct as HTML
+func (t atomText) html() string {
+	switch t.Type {
+	case "xhtml":
+		return strings.TrimSpace(t.Inner)
+	case "html":
+		return strings.TrimSpace(t.Text)
+	}
+	return html.EscapeString(strings.TrimSpace(t.Text))
+}
+
+// plain returns the text construct as plain text
+func (t atomText) plain() string {
+	if t.Type == "html" || t.Type == "xhtml" {
+		return textContent(t.html())
+	}
+	return t.Text
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// atomHref returns the alternate link of an Atom feed or entry
+func atomHref(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+func (e *atomEntry) entry(feedURL string) *Entry {
+	link := atomHref(e.Links)
+	if link == "" && strings.HasPrefix(strings.TrimSpace(e.ID), "http") {
+		link = strings.TrimSpace(e.ID)
+	}
+	var authors []string
+	for _, author := range e.Authors {
+		if name := collapse(author.Name); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	return &Entry{
+		Title:     collapse(e.Title.plain()),
+		Link:      resolve(feedURL, link),
+		Published: formatDate(first(e.Published, e.Updated)),
+		Author:    strings.Join(authors, ", "),
+		Summary:   first(e.Summary.html(), e.Content.html()),
+	}
+}
+
+// Layouts of RFC 822 dates in RSS and RFC 3339 dates in Atom, with the
+// variations found in practice
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// formatDate writes a date as YYYY-MM-DD, keeping dates it cannot read as
+// they are
+func formatDate(date string) string {
+	date = collapse(date)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return date
+}
+
+// Digest renders the feed as an HTML page: the feed title, linking to the
+// site, and description, then a section for each entry with its linked
+// title, date, author and summary, or its full article when one was fetched
+func (f *Feed) Digest() []byte {
+	var b strings.Builder
+	b.WriteString("<html><head>")
+	if f.Title != "" {
+		b.WriteString("<title>" + html.EscapeString(f.Title) + "</title>")
+	}
+	if f.Description != "" {
+		b.WriteString(`<meta name="description" content="` + html.EscapeString(f.Description) + `">`)
+	}
+	b.WriteString("</head><body>\n")
+	if f.Title != "" {
+		title := html.EscapeString(f.Title)
+		if f.Link != "" {
+			title = `<a href="` + html.EscapeString(f.Link) + `">` + title + "</a>"
+		}
+		b.WriteString("<h1>" + title + "</h1>\n")
+	}
+	if f.Description != "" {
+		b.WriteString("<p>" + html.EscapeString(f.Description) + "</p>\n")
+	}
+
+	for _, entry := range f.Entries {
+		title := html.EscapeString(first(entry.Title, entry.Link, "Untitled"))
+		if entry.Link != "" {
+			title = `<a href="` + html.EscapeString(entry.Link) + `">` + title + "</a>"
+		}
+		b.WriteString("<h2>" + title + "</h2>\n")
+		var byline []string
+		if entry.Published != "" {
+			byline = append(byline, html.EscapeString(entry.Published))
+		}
+		if entry.Author != "" {
+			byline = append(byline, html.EscapeString(entry.Author))
+		}
+		if len(byline) > 0 {
+			b.WriteString("<p>" + strings.Join(byline, " · ") + "</p>\n")
+		}
+
+		if article := articleBody(entry.Article); article != "" {
+			b.WriteString("<div>" + article + "</div>\n")
+			continue
+		}
+		if entry.Err != nil {
+			b.WriteString("<p><em>" + html.EscapeString(fmt.Sprintf("Full article not fetched: %v", entry.Err)) + "</em></p>\n")
+		}
+		if summary := shorten(textContent(entry.Summary), SummaryLength); summary != "" {
+			b.WriteString("<p>" + html.EscapeString(summary) + "</p>\n")
+		}
+	}
+	b.WriteString("</body></html>")
+	return []byte(b.String())
+}
+
+// articleBody returns the content of an article page, with its headings
+// moved two levels down to sit below the entry title
+func articleBody(article []byte) string {
+	if len(article) == 0 {
+		return ""
+	}
+	doc, err := html.Parse(bytes.NewReader(article))
+	if err != nil {
+		return ""
+	}
+	body := findElement(doc, "body")
+	if body == nil {
+		return ""
+	}
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6' {
+			n.Data = "h" + string(min(n.Data[1]+2, '6'))
+			n.DataAtom = 0
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(body)
+
+	var b bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&b, c); err != nil {
+			return ""
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// shorten cuts text to at most n characters at a word boundary
+func shorten(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	cut := string([]rune(text)[:n])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:.") + "…"
+}
+
+// textContent returns the text of an HTML fragment with its whitespace
+// collapsed
+func textContent(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return collapse(fragment)
+	}
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style"):
+		default:
+			if n.Type == html.ElementNode && !inlineTags[n.Data] {
+				// Block elements and line breaks separate words
+				b.WriteString(" ")
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				visit(c)
+			}
+		}
+	}
+	for _, n := range nodes {
+		visit(n)
+	}
+	return collapse(b.String())
+}
+
+// Elements that do not separate words
+var inlineTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "cite": true, "code": true, "em": true,
+	"i": true, "kbd": true, "mark": true, "q": true, "s": true, "small": true,
+	"span": true, "strong": true, "sub": true, "sup": true, "time": true, "u": true,
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// resolve makes a link absolute against the feed URL
+func resolve(feedURL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
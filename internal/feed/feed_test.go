@@ -0,0 +1,146 @@
+package feed
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const rss = `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="/feed.xsl"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+	<title>Example Blog</title>
+	<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+	<link>https://example.com/</link>
+	<description>Notes on building things</description>
+	<item>
+		<title>Shipping the new pipeline</title>
+		<link>/blog/pipeline</link>
+		<pubDate>Fri, 14 Mar 2025 09:30:00 +0000</pubDate>
+		<dc:creator>Ada Lovelace</dc:creator>
+		<description>&lt;p&gt;How we rebuilt the &lt;em&gt;build&lt;/em&gt; pipeline.&lt;/p&gt;</description>
+		<content:encoded><![CDATA[<p>The full story.</p>]]></content:encoded>
+	</item>
+	<item>
+		<guid>https://example.com/blog/notes</guid>
+		<pubDate>Sometime in spring</pubDate>
+		<content:encoded><![CDATA[<p>Short notes.</p>]]></content:encoded>
+	</item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title type="html">Example &amp;lt;Dev&amp;gt; Log</title>
+	<link rel="self" href="https://example.com/atom.xml"/>
+	<link href="https://example.com/"/>
+	<entry>
+		<title>Release 2.0</title>
+		<link rel="alternate" href="https://example.com/releases/2.0"/>
+		<id>tag:example.com,2025:release-2.0</id>
+		<updated>2025-04-01T12:00:00Z</updated>
+		<author><name>Grace</name></author>
+		<author><name>Alan</name></author>
+		<summary type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Faster and <b>smaller</b>.</p></div></summary>
+	</entry>
+</feed>`
+
+func TestIsFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"rss", rss, true},
+		{"atom", atomFeed, true},
+		{"rss 1.0", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"></rdf:RDF>`, true},
+		{"html", "<!DOCTYPE html>\n<html><head><title>Feed</title></head></html>", false},
+		{"other xml", `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`, false},
+		{"text", "feed me", false},
+		{"json", `{"rss": true}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFeed([]byte(tt.content)); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRSS(t *testing.T) {
+	f, err := Parse([]byte(rss), "https://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Example Blog" || f.Link != "https://example.com/" || f.Description != "Notes on building things" {
+		t.Errorf("unexpected feed: %+v", f)
+	}
+
+	expected := []*Entry{
+		{
+			Title:     "Shipping the new pipeline",
+			Link:      "https://example.com/blog/pipeline",
+			Published: "2025-03-14",
+			Author:    "Ada Lovelace",
+			Summary:   "<p>How we rebuilt the <em>build</em> pipeline.</p>",
+		},
+		{
+			Link:      "https://example.com/blog/notes",
+			Published: "Sometime in spring",
+			Summary:   "<p>Short notes.</p>",
+		},
+	}
+	if !reflect.DeepEqual(f.Entries, expected) {
+		for _, entry := range f.Entries {
+			t.Logf("%+v", *entry)
+		}
+		t.Error("unexpected entries")
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	f, err := Parse([]byte(atomFeed), "https://example.com/atom.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Example <Dev> Log" || f.Link != "https://example.com/" {
+		t.Errorf("unexpected feed: %+v", f)
+	}
+	if len(f.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(f.Entries))
+	}
+	entry := f.Entries[0]
+	if entry.Title != "Release 2.0" || entry.Link != "https://example.com/releases/2.0" || entry.Published != "2025-04-01" || entry.Author != "Grace, Alan" {
+		t.Errorf("unexpected entry: %+v", *entry)
+	}
+	if !strings.Contains(entry.Summary, "<p>Faster and <b>smaller</b>.</p>") {
+		t.Errorf("expected the XHTML summary, got %q", entry.Summary)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	f, err := Parse([]byte(rss), "https://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Entries[0].Article = []byte(`<html><head><title>Pipeline</title></head><body><h1>Shipping</h1><p>All of it.</p></body></html>`)
+	f.Entries[1].Err = errors.New("timeout")
+	f.Entries = append(f.Entries, &Entry{Title: "Long", Summary: strings.Repeat("word ", 100)})
+
+	digest := string(f.Digest())
+	for _, part := range []string{
+		`<title>Example Blog</title><meta name="description" content="Notes on building things">`,
+		`<h1><a href="https://example.com/">Example Blog</a></h1>`,
+		`<h2><a href="https://example.com/blog/pipeline">Shipping the new pipeline</a></h2>` + "\n<p>2025-03-14 · Ada Lovelace</p>\n<div><h3>Shipping</h3><p>All of it.</p></div>",
+		`<h2><a href="https://example.com/blog/notes">https://example.com/blog/notes</a></h2>` + "\n<p>Sometime in spring</p>\n<p><em>Full article not fetched: timeout</em></p>\n<p>Short notes.</p>",
+		"<h2>Long</h2>\n<p>" + strings.Repeat("word ", 59) + "word…</p>",
+	} {
+		if !strings.Contains(digest, part) {
+			t.Errorf("expected the digest to contain:\n%s\ngot:\n%s", part, digest)
+		}
+	}
+}
@@ -5,22 +5,186 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/nathabonfim59/md-fetch/internal/assets"
 	"github.com/nathabonfim59/md-fetch/internal/browser"
+	"github.com/nathabonfim59/md-fetch/internal/chunks"
 	"github.com/nathabonfim59/md-fetch/internal/converter"
+	"github.com/nathabonfim59/md-fetch/internal/feed"
+	"github.com/nathabonfim59/md-fetch/internal/metadata"
+	"github.com/nathabonfim59/md-fetch/internal/rules"
+	"github.com/nathabonfim59/md-fetch/internal/tokens"
 )
 
 type ContentType int
 
+// DefaultAssetsDir is where downloaded images are saved, relative to the
+// Markdown output
+const DefaultAssetsDir = "assets"
+
 const (
 	Html ContentType = iota
 	Plaintext
 	Json
+	Pdf
+	Feed
+)
+
+// Bounds on fetching the articles of feed entries: how many are fetched at
+// the same time, how many of the first entries are fetched at all, and how
+// long fetching them all may take
+const (
+	feedWorkers    = 4
+	maxFeedEntries = 20
+	feedTimeout    = 2 * time.Minute
 )
 
+// Options configures how fetched content is processed. Options are shared by
+// the CLI, the configuration file and API requests, so every field carries
+// json and yaml tags.
+type Options struct {
+	browser.CleaningOptions `yaml:",inline"`
+	browser.RenderOptions   `yaml:",inline"`
+	converter.Options       `yaml:",inline"`
+
+	Format      converter.Format `json:"format,omitempty" yaml:"format"`             // Output format, Markdown by default
+	FrontMatter bool             `json:"front_matter,omitempty" yaml:"front_matter"` // Prepend the page metadata as YAML front matter to Markdown
+
+	StructuredData bool `json:"structured_data,omitempty" yaml:"structured_data"` // Extract JSON-LD, microdata and RDFa items
+
+	FeedEntries bool `json:"feed_entries,omitempty" yaml:"feed_entries"` // Fetch the full article of the first feed entries into the digest
+
+	MaxTokens int             `json:"max_tokens,omitempty" yaml:"max_tokens"` // Truncate the output to this many tokens, 0 for no limit
+	Tokenizer tokens.Encoding `json:"tokenizer,omitempty" yaml:"tokenizer"`   // Tokenizer tokens are counted with
+
+	// Only API responses report token counts, so counting them is requested
+	// per request; with MaxTokens they are always counted
+	CountTokens bool `json:"count_tokens,omitempty" yaml:"-"`
+
+	Chunk        bool `json:"chunk,omitempty" yaml:"chunk"`                 // Split Markdown into chunks along its headings
+	ChunkSize    int  `json:"chunk_size,omitempty" yaml:"chunk_size"`       // Maximum tokens of a chunk
+	ChunkOverlap int  `json:"chunk_overlap,omitempty" yaml:"chunk_overlap"` // Tokens a chunk repeats from the previous one
+
+	// Site rules are read from the local file system, so they can only be
+	// configured by the CLI and the configuration file, never by API requests
+	RulesDir string     `json:"-" yaml:"rules_dir"`
+	Rules    *rules.Set `json:"-" yaml:"-"`
+
+	// Downloaded images are written to the local file system as well, so
+	// the assets directory cannot be set by API requests either
+	AssetsDir string `json:"-" yaml:"assets_dir"`
+}
+
+// DefaultOptions returns the default processing configuration
+func DefaultOptions() *Options {
+	return &Options{
+		CleaningOptions: *browser.DefaultCleaningOptions(),
+		RenderOptions:   *browser.DefaultRenderOptions(),
+		Options:         *converter.DefaultOptions(),
+		Format:          converter.FormatMarkdown,
+		Tokenizer:       tokens.CL100K,
+		ChunkSize:       chunks.DefaultSize,
+		ChunkOverlap:    chunks.DefaultOverlap,
+	}
+}
+
+// Validate reports whether the options can be used for fetching
+func (o *Options) Validate() error {
+	if err := o.CleaningOptions.Validate(); err != nil {
+		return err
+	}
+	if !o.Format.Valid() {
+		return fmt.Errorf("invalid format %q: must be one of %s", o.Format, converter.Formats)
+	}
+	if err := o.Tokenizer.Validate(); err != nil {
+		return err
+	}
+	if o.MaxTokens < 0 {
+		return fmt.Errorf("invalid max tokens %d: must not be negative", o.MaxTokens)
+	}
+	if o.MaxTokens > 0 && (o.Format == converter.FormatHTML || o.Format == converter.FormatJSON) {
+		return fmt.Errorf("max tokens is not supported by the %s format", o.Format)
+	}
+	if o.ChunkSize < 0 {
+		return fmt.Errorf("invalid chunk size %d: must not be negative", o.ChunkSize)
+	}
+	if o.ChunkOverlap < 0 || (o.ChunkSize > 0 && o.ChunkOverlap >= o.ChunkSize) {
+		return fmt.Errorf("invalid chunk overlap %d: must be between 0 and the chunk size", o.ChunkOverlap)
+	}
+	if o.Chunk && o.Format != converter.FormatMarkdown {
+		return fmt.Errorf("chunking is not supported by the %s format", o.Format)
+	}
+	return o.Options.Validate()
+}
+
+// LoadRules loads the site rules from RulesDir
+func (o *Options) LoadRules() error {
+	set, err := rules.LoadDir(o.RulesDir)
+	if err != nil {
+		return err
+	}
+	o.Rules = set
+	return nil
+}
+
+// EnableImageDownloads makes the download image mode save images into
+// AssetsDir, which is relative to outputDir, the directory the Markdown is
+// written to. Images are linked relative to outputDir.
+func (o *Options) EnableImageDownloads(outputDir string) {
+	dir := o.AssetsDir
+	if dir == "" {
+		dir = DefaultAssetsDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(outputDir, dir)
+	}
+	prefix, err := filepath.Rel(outputDir, dir)
+	if err != nil {
+		prefix = dir
+	}
+	o.DownloadImage = assets.NewDownloader(dir, filepath.ToSlash(prefix)).Download
+}
+
 // FetchContent retrieves and processes content from a URL using the specified browser
 func FetchContent(urlStr string, browserType string) (string, error) {
+	return FetchContentWithOptions(urlStr, browserType, DefaultOptions())
+}
+
+// FetchContentWithOptions retrieves and processes content from a URL using the
+// specified browser and processing options
+func FetchContentWithOptions(urlStr string, browserType string, opts *Options) (string, error) {
+	result, err := Fetch(urlStr, browserType, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// Result is a fetched page
+type Result struct {
+	Content   string
+	Metadata  *metadata.Metadata // nil for content that is not HTML
+	Tokens    int                // Token count of Content, with CountTokens or MaxTokens
+	Truncated bool               // Whether Content was cut to MaxTokens
+	Chunks    []chunks.Chunk     // Chunks of Content, when chunking
+
+	StructuredData []metadata.Item // JSON-LD, microdata and RDFa items, when extracted
+}
+
+// Fetch retrieves and processes content from a URL like
+// FetchContentWithOptions, also returning the page metadata
+func Fetch(urlStr string, browserType string, opts *Options) (*Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Images == converter.ImageDownload && opts.DownloadImage == nil {
+		return nil, fmt.Errorf("images mode download requires an assets directory")
+	}
+
 	// Validate URL
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https" && !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://")) {
@@ -29,10 +193,103 @@ func FetchContent(urlStr string, browserType string) (string, error) {
 			urlStr = "https://" + urlStr
 			parsedURL, err = url.Parse(urlStr)
 			if err != nil {
-				return "", fmt.Errorf("invalid URL %q: %v", urlStr, err)
+				return nil, fmt.Errorf("invalid URL %q: %v", urlStr, err)
 			}
 		} else {
-			return "", fmt.Errorf("invalid URL %q: must use http or https scheme", urlStr)
+			return nil, fmt.Errorf("invalid URL %q: must use http or https scheme", urlStr)
+		}
+	}
+
+	body, rule, err := fetchBody(urlStr, parsedURL, browserType, opts)
+	if err != nil {
+		return nil, err
+	}
+	if detectContentType(body) == Feed {
+		// Feeds are rendered as an HTML digest, which is then converted
+		// like any page
+		f, err := feed.Parse(body, urlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feed: %v", err)
+		}
+		if opts.FeedEntries {
+			fetchEntries(f, browserType, opts)
+		}
+		body = f.Digest()
+	}
+
+	result := &Result{}
+	if detectContentType(body) == Html {
+		result.Metadata = metadata.Extract(body, urlStr)
+		if opts.StructuredData {
+			result.StructuredData = metadata.StructuredData(body, urlStr)
+		}
+	}
+
+	content, err := formatContent(body, urlStr, result, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Post-processing edits text, which would break the JSON document
+	if rule != nil && opts.Format != converter.FormatJSON {
+		content = rule.Process(content)
+	}
+
+	frontMatter := ""
+	if result.Metadata != nil && isMarkdown(opts.Format) && (opts.FrontMatter || len(result.StructuredData) > 0) {
+		// Structured data is written to the front matter even when the
+		// rest of the metadata is not
+		page := metadata.Metadata{}
+		if opts.FrontMatter {
+			page = *result.Metadata
+		}
+		page.StructuredData = result.StructuredData
+		frontMatter = page.FrontMatter()
+	}
+	if opts.MaxTokens > 0 {
+		// The front matter is part of the budget, and left out when it
+		// does not leave any room for the content
+		budget := opts.MaxTokens - tokens.Count(frontMatter, opts.Tokenizer)
+		if budget <= 0 {
+			frontMatter = ""
+			budget = opts.MaxTokens
+			result.Truncated = true
+		}
+		var truncated bool
+		content, truncated = tokens.Truncate(content, budget, opts.Tokenizer)
+		result.Truncated = result.Truncated || truncated
+	}
+
+	result.Content = frontMatter + content
+	if opts.CountTokens || opts.MaxTokens > 0 {
+		result.Tokens = tokens.Count(result.Content, opts.Tokenizer)
+	}
+	if opts.Chunk {
+		// The front matter is left out of the chunks, but offsets are
+		// within Content
+		result.Chunks = chunks.Split(content, urlStr, opts.ChunkSize, opts.ChunkOverlap, opts.Tokenizer)
+		shift := utf8.RuneCountInString(frontMatter)
+		for i := range result.Chunks {
+			result.Chunks[i].Start += shift
+			result.Chunks[i].End += shift
+		}
+	}
+	return result, nil
+}
+
+// fetchBody loads a URL with the browser, applying the site rule matching
+// it. The text of PDF documents is returned rebuilt as HTML.
+func fetchBody(urlStr string, parsedURL *url.URL, browserType string, opts *Options) ([]byte, *rules.Rule, error) {
+	// Apply the site rule matching the URL, if any
+	cleaningOpts := opts.CleaningOptions
+	renderOpts := opts.RenderOptions
+	rule := opts.Rules.Match(parsedURL)
+	if rule != nil {
+		rule.ApplyCleaning(&cleaningOpts)
+		if wait := rule.WaitDuration(); wait > 0 {
+			renderOpts.Wait = wait
+		}
+		if browserType == "" {
+			browserType = rule.Browser
 		}
 	}
 
@@ -45,30 +302,158 @@ func FetchContent(urlStr string, browserType string) (string, error) {
 		b, browserErr = browser.NewBrowser(browserType)
 	}
 	if browserErr != nil {
-		return "", fmt.Errorf("failed to initialize browser: %v", browserErr)
+		return nil, nil, fmt.Errorf("failed to initialize browser: %v", browserErr)
 	}
+	b.SetCleaningOptions(&cleaningOpts)
+	b.SetRenderOptions(&renderOpts)
 
 	// Fetch content
 	body, fetchErr := b.Fetch(urlStr)
 	if fetchErr != nil {
-		return "", fmt.Errorf("failed to fetch content: %v", fetchErr)
+		// Navigation failures are wrapped so callers can match them with
+		// errors.Is against the browser.Err* kinds
+		return nil, nil, fmt.Errorf("failed to fetch content: %w", fetchErr)
+	}
+	if detectContentType(body) == Pdf {
+		// The text of PDF documents is rebuilt as HTML, which is then
+		// converted like any page
+		var err error
+		if body, err = converter.PDFToHTML(body); err != nil {
+			return nil, nil, fmt.Errorf("failed to read PDF: %v", err)
+		}
+	}
+	return body, rule, nil
+}
+
+// fetchEntries fetches the full article of the first entries of a feed, a
+// few at a time. Failures are recorded on the entries, which keep their
+// summary, as are the articles still pending when feedTimeout is up.
+func fetchEntries(f *feed.Feed, browserType string, opts *Options) {
+	type article struct {
+		entry *feed.Entry
+		body  []byte
+		err   error
+	}
+
+	entries := f.Entries[:min(len(f.Entries), maxFeedEntries)]
+	// Buffered for every entry, so workers finishing after the deadline
+	// do not block
+	results := make(chan article, len(entries))
+	expired := make(chan struct{})
+	workers := make(chan struct{}, feedWorkers)
+	pending := make(map[*feed.Entry]bool)
+	for _, entry := range entries {
+		parsedURL, err := url.Parse(entry.Link)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			continue
+		}
+		pending[entry] = true
+		go func(entry *feed.Entry) {
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			select {
+			case <-expired:
+				return
+			default:
+			}
+
+			body, _, err := fetchBody(entry.Link, parsedURL, browserType, opts)
+			if err == nil && detectContentType(body) != Html {
+				err = fmt.Errorf("%s is not an HTML page", entry.Link)
+			}
+			results <- article{entry: entry, body: body, err: err}
+		}(entry)
 	}
 
-	// Check for Chrome error pages
-	bodyStr := string(body)
-	if strings.Contains(bodyStr, "This site can't be reached") ||
-		strings.Contains(bodyStr, "DNS_PROBE_FINISHED_NXDOMAIN") {
-		return "", fmt.Errorf("failed to fetch content: site cannot be reached")
+	timeout := time.NewTimer(feedTimeout)
+	defer timeout.Stop()
+	for len(pending) > 0 {
+		select {
+		case a := <-results:
+			delete(pending, a.entry)
+			if a.err != nil {
+				a.entry.Err = a.err
+			} else {
+				a.entry.Article = a.body
+			}
+		case <-timeout.C:
+			close(expired)
+			for entry := range pending {
+				entry.Err = fmt.Errorf("timed out after %v fetching the feed entries", feedTimeout)
+			}
+			return
+		}
+	}
+}
+
+// Document is a page in the JSON output format
+type Document struct {
+	URL            string             `json:"url"`
+	Metadata       *metadata.Metadata `json:"metadata,omitempty"`
+	StructuredData []metadata.Item    `json:"structured_data,omitempty"`
+	Blocks         []*converter.Block `json:"blocks"`
+}
+
+// formatContent writes a fetched body in the output format of opts, the
+// JSON format including the metadata and structured data of page. Bodies
+// that are not HTML are written as they are by the text, HTML and AsciiDoc
+// formats.
+func formatContent(body []byte, pageURL string, page *Result, opts *Options) (string, error) {
+	contentType := detectContentType(body)
+	switch {
+	case isMarkdown(opts.Format):
+		return convertContent(body, &opts.Options)
+	case opts.Format == converter.FormatJSON:
+		return formatJSON(body, contentType, pageURL, page, &opts.Options)
+	case contentType != Html || opts.Format == converter.FormatHTML:
+		return string(body), nil
+	case opts.Format == converter.FormatText:
+		return converter.ConvertToText(body), nil
+	case opts.Format == converter.FormatAsciiDoc:
+		return converter.ConvertToAsciiDoc(body, &opts.Options), nil
 	}
+	return "", fmt.Errorf("unsupported format %q", opts.Format)
+}
 
+// formatJSON writes a fetched body as a Document
+func formatJSON(body []byte, contentType ContentType, pageURL string, page *Result, opts *converter.Options) (string, error) {
+	doc := &Document{URL: pageURL, Metadata: page.Metadata, StructuredData: page.StructuredData}
+	switch contentType {
+	case Html:
+		doc.Blocks = converter.ExtractBlocks(body)
+		if opts.NormalizeHeadings {
+			converter.NormalizeHeadings(doc.Blocks)
+		}
+	case Json:
+		doc.Blocks = []*converter.Block{{Type: converter.BlockCode, Language: "json", Text: string(body)}}
+	default:
+		doc.Blocks = []*converter.Block{{Type: converter.BlockParagraph, Text: string(body)}}
+	}
+	if doc.Blocks == nil {
+		doc.Blocks = []*converter.Block{}
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding JSON: %v", err)
+	}
+	return string(out), nil
+}
+
+func isMarkdown(format converter.Format) bool {
+	return format == "" || format == converter.FormatMarkdown
+}
+
+// convertContent converts a fetched body to Markdown based on its content type
+func convertContent(body []byte, convertOpts *converter.Options) (string, error) {
 	// Try to determine content type from first few bytes
 	contentType := detectContentType(body)
 
 	switch contentType {
 	case Html:
-		return converter.ConvertToMarkdown(body), nil
+		return converter.ConvertToMarkdownWithOptions(body, convertOpts), nil
 	case Plaintext:
-		return bodyStr, nil
+		return string(body), nil
 	case Json:
 		var prettyJSON bytes.Buffer
 		err := json.Indent(&prettyJSON, body, "", "  ")
@@ -82,6 +467,14 @@ func FetchContent(urlStr string, browserType string) (string, error) {
 }
 
 func detectContentType(content []byte) ContentType {
+	// PDF documents are recognized by their magic bytes
+	if converter.IsPDF(content) {
+		return Pdf
+	}
+	if feed.IsFeed(content) {
+		return Feed
+	}
+
 	// Simple content type detection based on content
 	s := strings.TrimSpace(string(content))
 
@@ -0,0 +1,315 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata describes a page, as declared in its <head> and JSON-LD
+type Metadata struct {
+	Title        string            `json:"title,omitempty" yaml:"title,omitempty"`
+	Description  string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Author       string            `json:"author,omitempty" yaml:"author,omitempty"`
+	Published    string            `json:"published,omitempty" yaml:"published,omitempty"`
+	Modified     string            `json:"modified,omitempty" yaml:"modified,omitempty"`
+	URL          string            `json:"url,omitempty" yaml:"url,omitempty"`
+	CanonicalURL string            `json:"canonical_url,omitempty" yaml:"canonical_url,omitempty"`
+	Language     string            `json:"language,omitempty" yaml:"language,omitempty"`
+	SiteName     string            `json:"site_name,omitempty" yaml:"site_name,omitempty"`
+	OpenGraph    map[string]string `json:"open_graph,omitempty" yaml:"open_graph,omitempty"` // og:* properties, without the prefix
+	Twitter      map[string]string `json:"twitter,omitempty" yaml:"twitter,omitempty"`       // twitter:* card fields, without the prefix
+
+	// StructuredData is only set for the front matter; other outputs list
+	// the items next to the metadata
+	StructuredData []Item `json:"-" yaml:"structured_data,omitempty"`
+}
+
+// Extract reads the metadata of an HTML page loaded from pageURL
+func Extract(content []byte, pageURL string) *Metadata {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return &Metadata{URL: pageURL}
+	}
+
+	h := readHead(doc)
+	ld := primaryJSONLD(h.jsonLD)
+
+	m := &Metadata{
+		Title:       first(h.og["title"], ldString(ld, "headline"), h.twitter["title"], ldString(ld, "name"), h.title),
+		Description: first(h.meta["description"], h.og["description"], h.twitter["description"], ldString(ld, "description")),
+		Author:      first(h.meta["author"], h.meta["article:author"], ldName(ld["author"]), h.meta["dc.creator"]),
+		Published: first(h.meta["article:published_time"], ldString(ld, "datePublished"),
+			h.meta["date"], h.meta["pubdate"], h.meta["publish-date"], h.meta["dc.date.issued"], h.meta["dc.date"]),
+		Modified:     first(h.meta["article:modified_time"], h.og["updated_time"], ldString(ld, "dateModified"), h.meta["last-modified"]),
+		URL:          pageURL,
+		CanonicalURL: resolve(pageURL, first(h.canonical, h.og["url"])),
+		Language:     first(h.lang, h.meta["content-language"], ldString(ld, "inLanguage"), strings.ReplaceAll(h.og["locale"], "_", "-")),
+		SiteName:     first(h.og["site_name"], ldName(ld["publisher"]), h.meta["application-name"]),
+	}
+	if len(h.og) > 0 {
+		m.OpenGraph = h.og
+	}
+	if len(h.twitter) > 0 {
+		m.Twitter = h.twitter
+	}
+	return m
+}
+
+// FrontMatter renders the metadata as a YAML front matter block, followed by
+// a blank line
+func (m *Metadata) FrontMatter() string {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m); err != nil || buf.String() == "{}\n" {
+		return ""
+	}
+	return "---\n" + buf.String() + "---\n\n"
+}
+
+// head holds the raw metadata found in a document
+type head struct {
+	title     string
+	lang      string
+	canonical string
+	meta      map[string]string // name, property and http-equiv, lower-cased
+	og        map[string]string
+	twitter   map[string]string
+	jsonLD    []map[string]interface{}
+}
+
+func readHead(doc *html.Node) *head {
+	h := &head{
+		meta:    make(map[string]string),
+		og:      make(map[string]string),
+		twitter: make(map[string]string),
+	}
+
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "html":
+				h.lang = strings.TrimSpace(attr(n, "lang"))
+			case "title":
+				if h.title == "" {
+					h.title = collapse(textContent(n))
+				}
+			case "link":
+				if h.canonical == "" && hasToken(attr(n, "rel"), "canonical") {
+					h.canonical = strings.TrimSpace(attr(n, "href"))
+				}
+			case "meta":
+				h.addMeta(n)
+			case "script":
+				h.addJSONLD(n)
+			case "body":
+				// Metadata lives in the head; JSON-LD is moved there by the
+				// cleaner, but pages served as-is may keep it in the body
+				walkScripts(n, h.addJSONLD)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+	return h
+}
+
+func (h *head) addMeta(n *html.Node) {
+	content := collapse(attr(n, "content"))
+	if content == "" {
+		return
+	}
+	for _, key := range []string{"property", "name", "http-equiv"} {
+		name := strings.ToLower(strings.TrimSpace(attr(n, key)))
+		if name == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, "og:"):
+			setOnce(h.og, strings.TrimPrefix(name, "og:"), content)
+		case strings.HasPrefix(name, "twitter:"):
+			setOnce(h.twitter, strings.TrimPrefix(name, "twitter:"), content)
+		}
+		setOnce(h.meta, name, content)
+	}
+}
+
+func (h *head) addJSONLD(n *html.Node) {
+	t, _, _ := strings.Cut(attr(n, "type"), ";")
+	if !strings.EqualFold(strings.TrimSpace(t), "application/ld+json") || hasAttr(n, HoistedAttr) {
+		return
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(textContent(n)), &data); err != nil {
+		return
+	}
+	h.jsonLD = append(h.jsonLD, flattenJSONLD(data)...)
+}
+
+// flattenJSONLD lists the objects of a JSON-LD document, expanding arrays
+// and @graph containers
+func flattenJSONLD(data interface{}) []map[string]interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		var nodes []map[string]interface{}
+		for _, item := range v {
+			nodes = append(nodes, flattenJSONLD(item)...)
+		}
+		return nodes
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return flattenJSONLD(graph)
+		}
+		return []map[string]interface{}{v}
+	}
+	return nil
+}
+
+// Types describing the page content itself, rather than the site or an
+// organization
+var contentTypes = []string{"article", "posting", "report", "webpage", "recipe", "product", "event", "course", "howto", "faqpage"}
+
+// primaryJSONLD picks the JSON-LD object describing the page content. Other
+// objects, such as the organization of a site footer, describe something
+// else, so there may be none.
+func primaryJSONLD(nodes []map[string]interface{}) map[string]interface{} {
+	for _, n := range nodes {
+		for _, t := range ldTypes(n) {
+			t = strings.ToLower(t)
+			for _, ct := range contentTypes {
+				if strings.HasSuffix(t, ct) {
+					return n
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func ldTypes(n map[string]interface{}) []string {
+	switch t := n["@type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func ldString(n map[string]interface{}, key string) string {
+	if s, ok := n[key].(string); ok {
+		return collapse(s)
+	}
+	return ""
+}
+
+// ldName reads a person or organization, given as a name, an object with a
+// name, or a list of either
+func ldName(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return collapse(v)
+	case map[string]interface{}:
+		return ldString(v, "name")
+	case []interface{}:
+		var names []string
+		for _, item := range v {
+			if name := ldName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+func resolve(pageURL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func setOnce(m map[string]string, key, value string) {
+	if _, ok := m[key]; !ok {
+		m[key] = value
+	}
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func walkScripts(n *html.Node, fn func(*html.Node)) {
+	if n.Type == html.ElementNode && n.Data == "script" {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkScripts(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+)
+
+const page = `<!DOCTYPE html>
+<html lang="en-US">
+<head>
+	<title>Fallback Title | Example Blog</title>
+	<meta name="description" content="How we rebuilt the   pipeline.">
+	<meta property="og:title" content="Rebuilding the Pipeline">
+	<meta property="og:site_name" content="Example Blog">
+	<meta property="og:type" content="article">
+	<meta property="og:image" content="https://example.com/cover.png">
+	<meta property="article:published_time" content="2024-03-01T10:00:00Z">
+	<meta name="twitter:card" content="summary_large_image">
+	<meta name="twitter:site" content="@example">
+	<link rel="canonical" href="/blog/pipeline">
+	<script type="application/ld+json">
+	{"@context": "https://schema.org", "@graph": [
+		{"@type": "WebSite", "name": "Example"},
+		{"@type": "BlogPosting", "headline": "Pipeline", "dateModified": "2024-03-05",
+		 "author": [{"@type": "Person", "name": "Ada Lovelace"}, {"@type": "Person", "name": "Alan Turing"}]}
+	]}
+	</script>
+</head>
+<body><p>Body</p></body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	m := Extract([]byte(page), "https://example.com/blog/pipeline?ref=feed")
+
+	tests := []struct {
+		field, got, expected string
+	}{
+		{"title", m.Title, "Rebuilding the Pipeline"},
+		{"description", m.Description, "How we rebuilt the pipeline."},
+		{"author", m.Author, "Ada Lovelace, Alan Turing"},
+		{"published", m.Published, "2024-03-01T10:00:00Z"},
+		{"modified", m.Modified, "2024-03-05"},
+		{"url", m.URL, "https://example.com/blog/pipeline?ref=feed"},
+		{"canonical_url", m.CanonicalURL, "https://example.com/blog/pipeline"},
+		{"language", m.Language, "en-US"},
+		{"site_name", m.SiteName, "Example Blog"},
+		{"og:image", m.OpenGraph["image"], "https://example.com/cover.png"},
+		{"twitter:card", m.Twitter["card"], "summary_large_image"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.field, tt.expected, tt.got)
+		}
+	}
+}
+
+func TestExtractFallbacks(t *testing.T) {
+	m := Extract([]byte(`<html><head><title> Plain  Page </title></head><body>
+		<script type="application/ld+json">{"@type": "NewsArticle", "description": "From JSON-LD", "author": "Grace Hopper", "publisher": {"name": "Daily"}}</script>
+	</body></html>`), "https://example.com/")
+
+	if m.Title != "Plain Page" || m.Description != "From JSON-LD" || m.Author != "Grace Hopper" || m.SiteName != "Daily" {
+		t.Errorf("unexpected metadata: %+v", m)
+	}
+	if m.OpenGraph != nil || m.Twitter != nil {
+		t.Errorf("expected no OpenGraph or Twitter fields, got %+v", m)
+	}
+}
+
+func TestExtractIgnoresOtherObjects(t *testing.T) {
+	m := Extract([]byte(`<html><head><title>Real Article Title</title>
+		<script type="application/ld+json">{"@type": "Organization", "name": "Acme Corp"}</script>
+		<script type="application/ld+json" `+HoistedAttr+`="">[{"@type": "Article", "name": "Footer Item"}]</script>
+	</head><body></body></html>`), "https://example.com/")
+
+	if m.Title != "Real Article Title" {
+		t.Errorf("expected the page title, got %q", m.Title)
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	m := &Metadata{Title: "Quotes: \"and\" colons", URL: "https://example.com/", OpenGraph: map[string]string{"type": "article"}}
+	expected := "---\ntitle: 'Quotes: \"and\" colons'\nurl: https://example.com/\nopen_graph:\n  type: article\n---\n\n"
+	if got := m.FrontMatter(); got != expected {
+		t.Errorf("\nexpected:\n%s\ngot:\n%s", expected, got)
+	}
+	if got := (&Metadata{}).FrontMatter(); got != "" {
+		t.Errorf("expected no front matter for empty metadata, got %q", got)
+	}
+	if !strings.HasSuffix(m.FrontMatter(), "---\n\n") {
+		t.Error("expected front matter to end with a blank line")
+	}
+}
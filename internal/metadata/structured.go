@@ -0,0 +1,346 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Item is a structured data item, such as a recipe, product, event or
+// article, as a JSON-LD object
+type Item = map[string]interface{}
+
+// HoistedAttr marks the JSON-LD scripts a cleaner built from the microdata
+// and RDFa items of a page. They are structured data, but do not describe
+// the page itself as authored JSON-LD does, so Extract ignores them.
+const HoistedAttr = "data-md-fetch-hoisted"
+
+// Vocabulary the types and properties of schema.org items are relative to
+const schemaOrg = "https://schema.org"
+
+// StructuredData reads the JSON-LD, microdata and RDFa items of an HTML
+// page loaded from pageURL. Microdata and RDFa items are converted to
+// JSON-LD objects, with nested items as nested objects.
+func StructuredData(content []byte, pageURL string) []Item {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	var items []Item
+	walkScripts(doc, func(n *html.Node) {
+		items = append(items, jsonLDItems(n)...)
+	})
+	items = append(items, MicrodataItems(doc, pageURL)...)
+	return append(items, RDFaItems(doc, pageURL)...)
+}
+
+// jsonLDItems returns the objects of a JSON-LD script, expanding @graph
+// containers, which pass their @context on to the objects
+func jsonLDItems(n *html.Node) []Item {
+	t, _, _ := strings.Cut(attr(n, "type"), ";")
+	if !strings.EqualFold(strings.TrimSpace(t), "application/ld+json") {
+		return nil
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(textContent(n)), &data); err != nil {
+		return nil
+	}
+
+	var items []Item
+	var visit func(data, context interface{})
+	visit = func(data, context interface{}) {
+		switch v := data.(type) {
+		case []interface{}:
+			for _, item := range v {
+				visit(item, context)
+			}
+		case map[string]interface{}:
+			if c, ok := v["@context"]; ok {
+				context = c
+			}
+			if graph, ok := v["@graph"]; ok {
+				visit(graph, context)
+				return
+			}
+			if _, ok := v["@context"]; !ok && context != nil {
+				v["@context"] = context
+			}
+			items = append(items, v)
+		}
+	}
+	visit(data, nil)
+	return items
+}
+
+// MicrodataItems reads the top-level microdata items below n: the elements
+// with an itemscope that are not themselves a property of another item
+func MicrodataItems(n *html.Node, pageURL string) []Item {
+	var items []Item
+	walkElements(n, func(e *html.Node) bool {
+		if hasAttr(e, "itemscope") && !hasAttr(e, "itemprop") {
+			items = append(items, microdataItem(e, pageURL))
+		}
+		return true
+	})
+	return items
+}
+
+func microdataItem(n *html.Node, pageURL string) Item {
+	item := Item{}
+	setTypes(item, strings.Fields(attr(n, "itemtype")), "")
+	if id := strings.TrimSpace(attr(n, "itemid")); id != "" {
+		item["@id"] = resolve(pageURL, id)
+	}
+
+	// Properties are the descendants up to the next item, and the elements
+	// listed in itemref
+	roots := []*html.Node{n}
+	for _, id := range strings.Fields(attr(n, "itemref")) {
+		if ref := elementByID(root(n), id); ref != nil {
+			roots = append(roots, ref)
+		}
+	}
+	for i, r := range roots {
+		visit := func(e *html.Node) bool {
+			for _, name := range strings.Fields(attr(e, "itemprop")) {
+				var value interface{}
+				if hasAttr(e, "itemscope") {
+					value = microdataItem(e, pageURL)
+				} else {
+					value = propertyValue(e, pageURL)
+				}
+				addProperty(item, propertyName(name), value)
+			}
+			return !hasAttr(e, "itemscope")
+		}
+		// An element listed in itemref can be a property itself
+		if i > 0 && !visit(r) {
+			continue
+		}
+		walkElements(r, visit)
+	}
+	return item
+}
+
+// RDFaItems reads the top-level RDFa items below n: the elements with a
+// typeof that are not the value of a property of another item
+func RDFaItems(n *html.Node, pageURL string) []Item {
+	var items []Item
+	var visit func(e *html.Node, vocab string)
+	visit = func(e *html.Node, vocab string) {
+		for c := e.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			v := vocab
+			if hasAttr(c, "vocab") {
+				v = strings.TrimSpace(attr(c, "vocab"))
+			}
+			if hasAttr(c, "typeof") && !hasAttr(c, "property") {
+				items = append(items, rdfaItem(c, v, pageURL))
+				continue
+			}
+			visit(c, v)
+		}
+	}
+	visit(n, "")
+	return items
+}
+
+func rdfaItem(n *html.Node, vocab, pageURL string) Item {
+	item := Item{}
+	setTypes(item, strings.Fields(attr(n, "typeof")), vocab)
+	if id := strings.TrimSpace(first(attr(n, "resource"), attr(n, "about"))); id != "" {
+		item["@id"] = resolve(pageURL, id)
+	}
+
+	var visit func(e *html.Node, vocab string)
+	visit = func(e *html.Node, vocab string) {
+		for c := e.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			v := vocab
+			if hasAttr(c, "vocab") {
+				v = strings.TrimSpace(attr(c, "vocab"))
+			}
+			if !hasAttr(c, "property") {
+				// An unrelated item nested in this one is not a property
+				if !hasAttr(c, "typeof") {
+					visit(c, v)
+				}
+				continue
+			}
+			for _, name := range strings.Fields(attr(c, "property")) {
+				var value interface{}
+				if hasAttr(c, "typeof") {
+					value = rdfaItem(c, v, pageURL)
+				} else if content, ok := attrValue(c, "content"); ok {
+					value = collapse(content)
+				} else if resource := first(attr(c, "resource"), attr(c, "href"), attr(c, "src")); resource != "" {
+					value = resolve(pageURL, resource)
+				} else {
+					value = propertyValue(c, pageURL)
+				}
+				addProperty(item, propertyName(name), value)
+			}
+			if !hasAttr(c, "typeof") {
+				visit(c, v)
+			}
+		}
+	}
+	visit(n, vocab)
+	return item
+}
+
+// setTypes sets the @type of an item, and its @context when the types
+// share a vocabulary such as schema.org
+func setTypes(item Item, types []string, vocab string) {
+	if len(types) == 0 {
+		return
+	}
+	context := ""
+	names := make([]interface{}, len(types))
+	for i, t := range types {
+		v, name := splitType(t, vocab)
+		if i == 0 {
+			context = v
+		} else if v != context {
+			context = ""
+		}
+		names[i] = name
+	}
+	if context == "" {
+		// Types of different vocabularies stay full URLs
+		for i, t := range types {
+			names[i] = t
+		}
+	} else {
+		item["@context"] = context
+	}
+	if len(names) == 1 {
+		item["@type"] = names[0]
+	} else {
+		item["@type"] = names
+	}
+}
+
+// splitType splits a type URL, such as https://schema.org/Recipe, into its
+// vocabulary and name. A type that is not a URL is relative to vocab, or
+// to schema.org, the vocabulary pages use in practice.
+func splitType(t, vocab string) (string, string) {
+	if i := strings.LastIndexAny(t, "/#"); i >= 0 && strings.Contains(t, "://") {
+		v := strings.TrimRight(t[:i], "/#")
+		if strings.TrimPrefix(strings.TrimPrefix(v, "http://"), "https://") == "schema.org" {
+			v = schemaOrg
+		}
+		return v, t[i+1:]
+	}
+	if _, name, ok := strings.Cut(t, ":"); ok {
+		// A CURIE such as schema:Recipe
+		t = name
+	}
+	if vocab == "" || strings.Contains(vocab, "schema.org") {
+		vocab = schemaOrg
+	}
+	return strings.TrimRight(vocab, "/#"), t
+}
+
+// propertyName returns a property name relative to its vocabulary
+func propertyName(name string) string {
+	if strings.Contains(name, "://") {
+		if i := strings.LastIndexAny(name, "/#"); i >= 0 {
+			return name[i+1:]
+		}
+	}
+	if _, local, ok := strings.Cut(name, ":"); ok {
+		return local
+	}
+	return name
+}
+
+// propertyValue reads the value of a microdata property element, which
+// depends on the element
+func propertyValue(n *html.Node, pageURL string) interface{} {
+	switch n.Data {
+	case "meta":
+		return collapse(attr(n, "content"))
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return resolve(pageURL, strings.TrimSpace(attr(n, "src")))
+	case "a", "area", "link":
+		return resolve(pageURL, strings.TrimSpace(attr(n, "href")))
+	case "object":
+		return resolve(pageURL, strings.TrimSpace(attr(n, "data")))
+	case "data", "meter":
+		return strings.TrimSpace(attr(n, "value"))
+	case "time":
+		if datetime, ok := attrValue(n, "datetime"); ok {
+			return strings.TrimSpace(datetime)
+		}
+	}
+	if content, ok := attrValue(n, "content"); ok {
+		return collapse(content)
+	}
+	return collapse(textContent(n))
+}
+
+// addProperty adds a value to a property, making it a list when the
+// property is repeated
+func addProperty(item Item, name string, value interface{}) {
+	if name == "" {
+		return
+	}
+	switch existing := item[name].(type) {
+	case nil:
+		item[name] = value
+	case []interface{}:
+		item[name] = append(existing, value)
+	default:
+		item[name] = []interface{}{existing, value}
+	}
+}
+
+// walkElements calls fn for every element below n, descending into an
+// element when fn returns true
+func walkElements(n *html.Node, fn func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || fn(c) {
+			walkElements(c, fn)
+		}
+	}
+}
+
+func elementByID(n *html.Node, id string) *html.Node {
+	var found *html.Node
+	walkElements(n, func(e *html.Node) bool {
+		if found == nil && attr(e, "id") == id {
+			found = e
+		}
+		return found == nil
+	})
+	return found
+}
+
+func root(n *html.Node) *html.Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	_, ok := attrValue(n, key)
+	return ok
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
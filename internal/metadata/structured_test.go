@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStructuredDataJSONLD(t *testing.T) {
+	items := StructuredData([]byte(page), "https://example.com/blog/pipeline")
+	if len(items) != 2 {
+		t.Fatalf("expected the 2 objects of the graph, got %d", len(items))
+	}
+	if items[1]["@type"] != "BlogPosting" || items[1]["@context"] != "https://schema.org" {
+		t.Errorf("expected a BlogPosting with the graph context, got %v", items[1])
+	}
+}
+
+func TestStructuredDataMicrodata(t *testing.T) {
+	content := `<html><body>
+	<div itemscope itemtype="https://schema.org/Recipe" itemref="nutrition">
+		<h1 itemprop="name">Pancakes</h1>
+		<img itemprop="image" src="/pancakes.jpg">
+		<time itemprop="totalTime" datetime="PT20M">20 minutes</time>
+		<span itemprop="recipeIngredient">Flour</span>
+		<span itemprop="recipeIngredient">Milk</span>
+		<div itemprop="author" itemscope itemtype="https://schema.org/Person">
+			<span itemprop="name">Ada</span>
+		</div>
+	</div>
+	<div id="nutrition" itemprop="nutrition" itemscope itemtype="https://schema.org/NutritionInformation">
+		<meta itemprop="calories" content="250 calories">
+	</div>
+	</body></html>`
+
+	items := StructuredData([]byte(content), "https://example.com/recipes/pancakes")
+	expected := []Item{{
+		"@context":         "https://schema.org",
+		"@type":            "Recipe",
+		"name":             "Pancakes",
+		"image":            "https://example.com/pancakes.jpg",
+		"totalTime":        "PT20M",
+		"recipeIngredient": []interface{}{"Flour", "Milk"},
+		"author":           Item{"@context": "https://schema.org", "@type": "Person", "name": "Ada"},
+		"nutrition":        Item{"@context": "https://schema.org", "@type": "NutritionInformation", "calories": "250 calories"},
+	}}
+	if !reflect.DeepEqual(items, expected) {
+		got, _ := json.MarshalIndent(items, "", "  ")
+		t.Errorf("unexpected items:\n%s", got)
+	}
+}
+
+func TestStructuredDataRDFa(t *testing.T) {
+	content := `<html><head><meta property="og:title" content="Launch"></head><body>
+	<div vocab="https://schema.org/" typeof="Event">
+		<h2 property="name">Launch Party</h2>
+		<span property="startDate" content="2025-06-01T19:00">June 1st</span>
+		<div property="location" typeof="Place">
+			<span property="name">Town Hall</span>
+			<a property="url" href="/venues/town-hall">Venue</a>
+		</div>
+	</div>
+	</body></html>`
+
+	items := StructuredData([]byte(content), "https://example.com/events")
+	expected := []Item{{
+		"@context":  "https://schema.org",
+		"@type":     "Event",
+		"name":      "Launch Party",
+		"startDate": "2025-06-01T19:00",
+		"location": Item{
+			"@context": "https://schema.org",
+			"@type":    "Place",
+			"name":     "Town Hall",
+			"url":      "https://example.com/venues/town-hall",
+		},
+	}}
+	if !reflect.DeepEqual(items, expected) {
+		got, _ := json.MarshalIndent(items, "", "  ")
+		t.Errorf("unexpected items:\n%s", got)
+	}
+}
@@ -0,0 +1,268 @@
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nathabonfim59/md-fetch/internal/browser"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule describes how to extract content from the pages of a site. Rules are
+// stored one per file as YAML or JSON.
+type Rule struct {
+	Name        string        `yaml:"name" json:"name"`
+	Domains     []string      `yaml:"domains" json:"domains"`         // Host names, matching the host and its subdomains
+	URLPattern  string        `yaml:"url_pattern" json:"url_pattern"` // Regular expression matched against the full URL
+	Select      string        `yaml:"select" json:"select"`           // CSS selector of the content to keep
+	Remove      string        `yaml:"remove" json:"remove"`           // CSS selector of elements to drop
+	Readability bool          `yaml:"readability" json:"readability"` // Extract the main article content
+	Wait        string        `yaml:"wait" json:"wait"`               // Time to let JavaScript run before capturing the page, e.g. "10s"
+	Browser     string        `yaml:"browser" json:"browser"`         // Preferred backend when none is requested
+	PostProcess []PostProcess `yaml:"post_process" json:"post_process"`
+
+	file       string
+	urlPattern *regexp.Regexp
+	wait       time.Duration
+}
+
+// PostProcess is a single transformation applied to the converted output.
+// Exactly one of Replace, TrimBefore or TrimAfter must be set.
+type PostProcess struct {
+	Replace    string `yaml:"replace" json:"replace"`         // Regular expression to replace
+	With       string `yaml:"with" json:"with"`               // Replacement for Replace, may use $1 style groups
+	TrimBefore string `yaml:"trim_before" json:"trim_before"` // Drop everything before the first match
+	TrimAfter  string `yaml:"trim_after" json:"trim_after"`   // Drop everything from the first match on
+
+	pattern *regexp.Regexp
+}
+
+// Set is an ordered collection of rules
+type Set struct {
+	rules []*Rule
+}
+
+// LoadDir reads every .yaml, .yml and .json rule file in dir, in file name
+// order. A missing directory yields an empty set.
+func LoadDir(dir string) (*Set, error) {
+	set := &Set{}
+	if dir == "" {
+		return set, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return set, nil
+		}
+		return nil, fmt.Errorf("failed to read rules directory: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rule, err := loadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		set.rules = append(set.rules, rule)
+	}
+	return set, nil
+}
+
+func loadFile(path string) (*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule file: %v", err)
+	}
+
+	// JSON is a subset of YAML, so one decoder handles both formats
+	rule := &Rule{file: path}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(rule); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid rule file %s: %v", path, err)
+	}
+	if err := rule.compile(); err != nil {
+		return nil, fmt.Errorf("invalid rule file %s: %v", path, err)
+	}
+	return rule, nil
+}
+
+// compile validates the rule and prepares its patterns
+func (r *Rule) compile() error {
+	if len(r.Domains) == 0 && r.URLPattern == "" {
+		return fmt.Errorf("rule must set domains or url_pattern")
+	}
+	for i, domain := range r.Domains {
+		r.Domains[i] = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "*.")
+	}
+
+	if r.URLPattern != "" {
+		pattern, err := regexp.Compile(r.URLPattern)
+		if err != nil {
+			return fmt.Errorf("invalid url_pattern: %v", err)
+		}
+		r.urlPattern = pattern
+	}
+
+	if r.Wait != "" {
+		wait, err := time.ParseDuration(r.Wait)
+		if err != nil {
+			return fmt.Errorf("invalid wait: %v", err)
+		}
+		r.wait = wait
+	}
+
+	if r.Browser != "" && !browser.IsSupported(r.Browser) {
+		return fmt.Errorf("unsupported browser type: %s", r.Browser)
+	}
+
+	cleaning := browser.CleaningOptions{Select: r.Select, Remove: r.Remove}
+	if err := cleaning.Validate(); err != nil {
+		return err
+	}
+
+	for i := range r.PostProcess {
+		if err := r.PostProcess[i].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PostProcess) compile() error {
+	var expr string
+	set := 0
+	for _, e := range []string{p.Replace, p.TrimBefore, p.TrimAfter} {
+		if e != "" {
+			expr = e
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("post_process step must set exactly one of replace, trim_before or trim_after")
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid post_process pattern: %v", err)
+	}
+	p.pattern = pattern
+	return nil
+}
+
+// Len returns the number of rules in the set
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+// Match returns the first rule matching the URL, or nil
+func (s *Set) Match(u *url.URL) *Rule {
+	if s == nil {
+		return nil
+	}
+	for _, r := range s.rules {
+		if r.Matches(u) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to the URL. When both domains and
+// a URL pattern are set, both must match.
+func (r *Rule) Matches(u *url.URL) bool {
+	if len(r.Domains) > 0 && !matchesDomain(r.Domains, u.Hostname()) {
+		return false
+	}
+	if r.urlPattern != nil && !r.urlPattern.MatchString(u.String()) {
+		return false
+	}
+	return true
+}
+
+func matchesDomain(domains []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyCleaning merges the rule selectors into the cleaning options. A
+// selector given explicitly in opts takes precedence over the rule's; removal
+// selectors are combined.
+func (r *Rule) ApplyCleaning(opts *browser.CleaningOptions) {
+	if opts.Select == "" {
+		opts.Select = r.Select
+	}
+	switch {
+	case opts.Remove == "":
+		opts.Remove = r.Remove
+	case r.Remove != "":
+		opts.Remove = opts.Remove + ", " + r.Remove
+	}
+	if r.Readability {
+		opts.Readability = true
+	}
+}
+
+// WaitDuration returns the time the rule allows JavaScript to run, or zero
+func (r *Rule) WaitDuration() time.Duration {
+	return r.wait
+}
+
+// Process applies the post-processing steps to the converted output
+func (r *Rule) Process(content string) string {
+	if len(r.PostProcess) == 0 {
+		return content
+	}
+	for _, p := range r.PostProcess {
+		switch {
+		case p.Replace != "":
+			content = p.pattern.ReplaceAllString(content, p.With)
+		case p.TrimBefore != "":
+			if loc := p.pattern.FindStringIndex(content); loc != nil {
+				content = content[loc[0]:]
+			}
+		case p.TrimAfter != "":
+			if loc := p.pattern.FindStringIndex(content); loc != nil {
+				content = content[:loc[0]]
+			}
+		}
+	}
+	return strings.TrimSpace(content)
+}
+
+// String identifies the rule in messages
+func (r *Rule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return filepath.Base(r.file)
+}
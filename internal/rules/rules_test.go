@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nathabonfim59/md-fetch/internal/browser"
+)
+
+func writeRule(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write rule: %v", err)
+	}
+}
+
+func mustParse(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	return u
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writeRule(t, dir, "10-docs.yaml", `name: docs
+domains: [docs.example.com]
+select: main.content
+remove: .feedback
+wait: 10s
+browser: chrome
+post_process:
+  - trim_after: "## Was this page helpful"
+  - replace: "(?m)^Edit on GitHub$\n?"
+    with: ""
+`)
+	writeRule(t, dir, "20-blog.json", `{
+		"domains": ["*.example.com"],
+		"url_pattern": "/blog/",
+		"readability": true
+	}`)
+	writeRule(t, dir, "README.txt", "not a rule")
+
+	set, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	if set.Len() != 2 {
+		t.Fatalf("expected 2 rules, got %d", set.Len())
+	}
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://docs.example.com/guide", "docs"},
+		{"https://api.docs.example.com/guide", "docs"},
+		{"https://www.example.com/blog/post", "20-blog.json"},
+		{"https://www.example.com/shop", ""},
+		{"https://example.org/blog/post", ""},
+	}
+	for _, tt := range tests {
+		rule := set.Match(mustParse(t, tt.url))
+		name := ""
+		if rule != nil {
+			name = rule.String()
+		}
+		if name != tt.expected {
+			t.Errorf("%s: expected rule %q, got %q", tt.url, tt.expected, name)
+		}
+	}
+
+	docs := set.Match(mustParse(t, "https://docs.example.com/"))
+	if docs.WaitDuration() != 10*time.Second {
+		t.Errorf("expected wait of 10s, got %s", docs.WaitDuration())
+	}
+
+	opts := &browser.CleaningOptions{Remove: ".ads"}
+	docs.ApplyCleaning(opts)
+	if opts.Select != "main.content" || opts.Remove != ".ads, .feedback" {
+		t.Errorf("unexpected cleaning options: %+v", opts)
+	}
+
+	content := "# Guide\n\nEdit on GitHub\nBody text\n\n## Was this page helpful\n\nYes / No"
+	if got := docs.Process(content); got != "# Guide\n\nBody text" {
+		t.Errorf("unexpected post-processed content: %q", got)
+	}
+}
+
+func TestLoadDirErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "no match criteria", content: "select: article\n"},
+		{name: "unknown key", content: "domains: [example.com]\nselector: article\n"},
+		{name: "invalid selector", content: "domains: [example.com]\nselect: \"article[\"\n"},
+		{name: "invalid wait", content: "domains: [example.com]\nwait: soon\n"},
+		{name: "invalid browser", content: "domains: [example.com]\nbrowser: netscape\n"},
+		{name: "invalid post process", content: "domains: [example.com]\npost_process:\n  - with: x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeRule(t, dir, "rule.yaml", tt.content)
+			if _, err := LoadDir(dir); err == nil {
+				t.Error("expected error loading rules")
+			}
+		})
+	}
+}
+
+func TestLoadDirMissing(t *testing.T) {
+	set, err := LoadDir(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("expected missing directory to be ignored, got %v", err)
+	}
+	if set.Len() != 0 {
+		t.Errorf("expected empty rule set, got %d rules", set.Len())
+	}
+}
@@ -4,28 +4,59 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"sync"
 
+	"github.com/nathabonfim59/md-fetch/internal/chunks"
+	"github.com/nathabonfim59/md-fetch/internal/converter"
 	"github.com/nathabonfim59/md-fetch/internal/fetcher"
+	"github.com/nathabonfim59/md-fetch/internal/metadata"
 )
 
 type Server struct {
-	port int
+	port     int
+	browser  string
+	defaults *fetcher.Options
 }
 
+// FetchRequest is the body of a fetch request. Every fetcher.Options field
+// can be set at the top level; omitted fields use the server defaults.
 type FetchRequest struct {
 	URLs    []string `json:"urls"`
-	Browser string  `json:"browser,omitempty"`
+	Browser string   `json:"browser,omitempty"`
+	fetcher.Options
 }
 
 type FetchResponse struct {
-	Results map[string]string `json:"results"`
-	Errors  map[string]string `json:"errors,omitempty"`
+	Results   map[string]string             `json:"results"`
+	Metadata  map[string]*metadata.Metadata `json:"metadata,omitempty"`
+	Tokens    map[string]int                `json:"tokens,omitempty"`
+	Truncated map[string]bool               `json:"truncated,omitempty"`
+	Chunks    []chunks.Chunk                `json:"chunks,omitempty"`
+
+	StructuredData map[string][]metadata.Item `json:"structured_data,omitempty"`
+	Errors         map[string]string          `json:"errors,omitempty"`
 }
 
 func New(port int) *Server {
 	return &Server{
-		port: port,
+		port:     port,
+		defaults: fetcher.DefaultOptions(),
+	}
+}
+
+// SetDefaults sets the browser and processing options used for fields a
+// request leaves out
+func (s *Server) SetDefaults(browserType string, opts *fetcher.Options) {
+	s.browser = browserType
+	s.defaults = opts
+
+	// The server never writes files, so a configured download image mode
+	// falls back to linking the remote images
+	if opts.Images == converter.ImageDownload {
+		defaults := *opts
+		defaults.Images = converter.ImageKeep
+		s.defaults = &defaults
 	}
 }
 
@@ -44,13 +75,28 @@ func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req FetchRequest
+	req := FetchRequest{Browser: s.browser, Options: *s.defaults}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	opts := req.Options
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Images == converter.ImageDownload {
+		http.Error(w, "images mode download is not available in the API", http.StatusBadRequest)
+		return
+	}
+
 	results := make(map[string]string)
+	pages := make(map[string]*metadata.Metadata)
+	counts := make(map[string]int)
+	truncated := make(map[string]bool)
+	pageChunks := make(map[string][]chunks.Chunk)
+	structured := make(map[string][]metadata.Item)
 	errors := make(map[string]string)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -59,16 +105,31 @@ func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
 		wg.Add(1)
 		go func(url string) {
 			defer wg.Done()
-			content, err := fetcher.FetchContent(url, req.Browser)
-			
+			result, err := fetcher.Fetch(url, req.Browser, &opts)
+
 			mu.Lock()
 			defer mu.Unlock()
-			
+
 			if err != nil {
 				errors[url] = err.Error()
 				return
 			}
-			results[url] = content
+			results[url] = result.Content
+			if opts.CountTokens || opts.MaxTokens > 0 {
+				counts[url] = result.Tokens
+			}
+			if result.Truncated {
+				truncated[url] = true
+			}
+			if result.Chunks != nil {
+				pageChunks[url] = result.Chunks
+			}
+			if len(result.StructuredData) > 0 {
+				structured[url] = result.StructuredData
+			}
+			if result.Metadata != nil {
+				pages[url] = result.Metadata
+			}
 		}(url)
 	}
 
@@ -77,6 +138,24 @@ func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
 	response := FetchResponse{
 		Results: results,
 	}
+	if len(pages) > 0 {
+		response.Metadata = pages
+	}
+	if len(counts) > 0 {
+		response.Tokens = counts
+	}
+	if len(truncated) > 0 {
+		response.Truncated = truncated
+	}
+	if len(structured) > 0 {
+		response.StructuredData = structured
+	}
+	// Chunks of all pages are listed in the order of the requested URLs
+	for i, url := range req.URLs {
+		if !slices.Contains(req.URLs[:i], url) {
+			response.Chunks = append(response.Chunks, pageChunks[url]...)
+		}
+	}
 	if len(errors) > 0 {
 		response.Errors = errors
 	}
@@ -118,6 +197,101 @@ paths:
                   type: string
                   enum: [chrome, firefox, curl]
                   description: Browser to use for fetching (optional)
+                keep_header:
+                  type: boolean
+                  description: Keep header elements (optional)
+                keep_footer:
+                  type: boolean
+                  description: Keep footer elements (optional)
+                keep_nav:
+                  type: boolean
+                  description: Keep navigation elements (optional)
+                keep_styles:
+                  type: boolean
+                  description: Keep inline and internal styles (optional)
+                keep_comments:
+                  type: boolean
+                  description: Keep HTML comments (optional)
+                keep_hidden:
+                  type: boolean
+                  description: Keep hidden and visually hidden elements (optional)
+                keep_relative_urls:
+                  type: boolean
+                  description: Keep relative link and image URLs instead of making them absolute (optional)
+                keep_tracking_params:
+                  type: boolean
+                  description: Keep tracking parameters and redirect wrappers in links (optional)
+                computed_visibility:
+                  type: boolean
+                  description: Detect elements hidden by stylesheets using the rendered page, Chrome only (optional)
+                inline_frames:
+                  type: boolean
+                  description: Inline the content of same-origin iframes, Chrome only (optional)
+                shadow_dom:
+                  type: boolean
+                  description: Include the content of open shadow roots, Chrome only (optional)
+                readability:
+                  type: boolean
+                  description: Extract only the main article content (optional)
+                select:
+                  type: string
+                  description: Keep only elements matching this CSS selector (optional)
+                remove:
+                  type: string
+                  description: Remove elements matching this CSS selector before conversion (optional)
+                links:
+                  type: string
+                  enum: [inline, reference, text, appendix]
+                  description: How to render links, defaults to inline (optional)
+                images:
+                  type: string
+                  enum: [keep, alt, drop]
+                  description: How to render images, defaults to keep. The download mode is only available in the CLI (optional)
+                complex_tables:
+                  type: string
+                  enum: [html, flatten]
+                  description: How to render tables with merged cells, nested tables or block content, defaults to html (optional)
+                normalize_headings:
+                  type: boolean
+                  description: Rewrite heading levels into an outline with a single H1, no skipped levels and no empty headings (optional)
+                toc:
+                  type: boolean
+                  description: Add a table of contents linking to the headings of each Markdown result (optional)
+                format:
+                  type: string
+                  enum: [markdown, text, html, json, asciidoc]
+                  description: Output format of each result, defaults to markdown. The json format returns the blocks and metadata of the page as a JSON document in a string (optional)
+                max_tokens:
+                  type: integer
+                  minimum: 0
+                  description: Truncate each result to this many tokens at a section boundary, not supported by the html and json formats (optional)
+                count_tokens:
+                  type: boolean
+                  description: Return the token count of each result in tokens, always done with max_tokens (optional)
+                tokenizer:
+                  type: string
+                  enum: [cl100k_base, o200k_base]
+                  description: Tokenizer tokens are counted for, defaults to cl100k_base (optional)
+                chunk:
+                  type: boolean
+                  description: Split each Markdown result along its headings into chunks, returned in chunks. Only supported by the markdown format (optional)
+                chunk_size:
+                  type: integer
+                  minimum: 0
+                  description: Maximum tokens of a chunk, defaults to 512 (optional)
+                chunk_overlap:
+                  type: integer
+                  minimum: 0
+                  description: Tokens a chunk repeats from the end of the previous chunk in its section, defaults to 64 (optional)
+                front_matter:
+                  type: boolean
+                  description: Prepend the page metadata to each Markdown result as YAML front matter (optional)
+                structured_data:
+                  type: boolean
+                  description: Return the JSON-LD, microdata and RDFa items of each page, such as recipes, products, events and articles, in structured_data (optional)
+                feed_entries:
+                  type: boolean
+                  description: For RSS and Atom feeds, fetch the full article of the first 20 entries into the digest instead of its summary (optional)
               required:
                 - urls
       responses:
@@ -133,6 +307,34 @@ paths:
                     additionalProperties:
                       type: string
                     description: Map of URLs to their fetched content
+                  metadata:
+                    type: object
+                    additionalProperties:
+                      $ref: '#/components/schemas/Metadata'
+                    description: Map of URLs to the metadata of HTML pages
+                  tokens:
+                    type: object
+                    additionalProperties:
+                      type: integer
+                    description: Map of URLs to the token count of their content, when count_tokens or max_tokens is set
+                  truncated:
+                    type: object
+                    additionalProperties:
+                      type: boolean
+                    description: URLs whose content was truncated to max_tokens
+                  chunks:
+                    type: array
+                    items:
+                      $ref: '#/components/schemas/Chunk'
+                    description: Chunks of all results in the order of the requested URLs, when chunk is set
+                  structured_data:
+                    type: object
+                    additionalProperties:
+                      type: array
+                      items:
+                        type: object
+                        description: A JSON-LD object with its @context, @type and properties
+                    description: Map of URLs to their structured data items, when structured_data is set
                   errors:
                     type: object
                     additionalProperties:
@@ -141,7 +343,67 @@ paths:
         '400':
           description: Invalid request
         '405':
-          description: Method not allowed`
+          description: Method not allowed
+
+components:
+  schemas:
+    Chunk:
+      type: object
+      description: A part of a result, within a single section
+      properties:
+        url:
+          type: string
+        index:
+          type: integer
+          description: Position of the chunk among the chunks of its URL
+        heading_path:
+          type: array
+          items:
+            type: string
+          description: Headings of the sections the chunk is in, outermost first
+        content:
+          type: string
+        start:
+          type: integer
+          description: Character offset of the chunk in the result
+        end:
+          type: integer
+          description: Character offset of the end of the chunk in the result
+        tokens:
+          type: integer
+          description: Token count of the chunk
+    Metadata:
+      type: object
+      description: Page metadata read from the head and JSON-LD; fields the page does not declare are left out
+      properties:
+        title:
+          type: string
+        description:
+          type: string
+        author:
+          type: string
+        published:
+          type: string
+        modified:
+          type: string
+        url:
+          type: string
+        canonical_url:
+          type: string
+        language:
+          type: string
+        site_name:
+          type: string
+        open_graph:
+          type: object
+          additionalProperties:
+            type: string
+          description: OpenGraph properties without the og prefix
+        twitter:
+          type: object
+          additionalProperties:
+            type: string
+          description: Twitter card fields without the twitter prefix`
 
 	w.Header().Set("Content-Type", "text/yaml")
 	fmt.Fprint(w, openAPISpec)
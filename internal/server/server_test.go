@@ -79,6 +79,80 @@ func TestHandleFetch(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "invalid selector request",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"urls":   []string{"https://example.com"},
+				"select": "article[",
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: nil,
+		},
+		{
+			name:   "invalid links mode request",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"urls":  []string{"https://example.com"},
+				"links": "footnotes",
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: nil,
+		},
+		{
+			name:   "invalid format request",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"urls":   []string{"https://example.com"},
+				"format": "pdf",
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: nil,
+		},
+		{
+			name:   "max tokens with json format request",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"urls":       []string{"https://example.com"},
+				"format":     "json",
+				"max_tokens": 4000,
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: nil,
+		},
+		{
+			name:   "chunk with text format request",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"urls":   []string{"https://example.com"},
+				"format": "text",
+				"chunk":  true,
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: nil,
+		},
+		{
+			name:   "chunk overlap too large request",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"urls":          []string{"https://example.com"},
+				"chunk":         true,
+				"chunk_size":    100,
+				"chunk_overlap": 100,
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: nil,
+		},
+		{
+			name:   "download images request",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"urls":   []string{"https://example.com"},
+				"images": "download",
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: nil,
+		},
 		{
 			name:         "invalid method",
 			method:      http.MethodGet,
@@ -0,0 +1,187 @@
+package tokens
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"embed"
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The rank files of the encodings, as published with OpenAI's tiktoken: each
+// line is a base64 token followed by its rank, lower ranks merging first
+//
+//go:embed ranks/*.tiktoken
+var rankFiles embed.FS
+
+// White space as the tokenizers see it, the Unicode White_Space property,
+// where \s of Go regular expressions only covers ASCII
+const space = `\t\n\v\f\r \x{85}\p{Z}`
+
+// splitters hold the pre-tokenizer patterns of the encodings, without the
+// \s+(?!\S) alternative Go regular expressions cannot express, which split
+// applies itself. Tokens never cross the pieces they split text into.
+var splitters = map[Encoding]*regexp.Regexp{
+	CL100K: regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^` + space + `\p{L}\p{N}]+[\r\n]*|[` + space + `]*[\r\n]+|[` + space + `]+`),
+	O200K: regexp.MustCompile(`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^` + space + `\p{L}\p{N}]+[\r\n/]*|[` + space + `]*[\r\n]+|[` + space + `]+`),
+}
+
+// bpe is the vocabulary of an encoding
+type bpe struct {
+	once  sync.Once
+	ranks map[string]int
+}
+
+var vocabularies = map[Encoding]*bpe{
+	CL100K: {},
+	O200K:  {},
+}
+
+// load reads the rank file of the encoding the first time it is used
+func (b *bpe) load(encoding Encoding) map[string]int {
+	b.once.Do(func() {
+		data, err := rankFiles.ReadFile("ranks/" + string(encoding) + ".tiktoken")
+		if err != nil {
+			panic(err) // embedded with the binary
+		}
+		b.ranks = make(map[string]int, bytes.Count(data, []byte("\n")))
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			token, rank, ok := bytes.Cut(scanner.Bytes(), []byte(" "))
+			if !ok {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(string(token))
+			if err != nil {
+				continue
+			}
+			if r, err := strconv.Atoi(string(rank)); err == nil {
+				b.ranks[string(decoded)] = r
+			}
+		}
+	})
+	return b.ranks
+}
+
+// split cuts text into the pieces the pre-tokenizer of the encoding would
+func split(text string, encoding Encoding) []string {
+	re := splitters[encoding]
+	var pieces []string
+	for pos := 0; pos < len(text); {
+		loc := re.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		end := pos + loc[1]
+		piece := text[pos+loc[0] : end]
+		// \s+(?!\S): a run of spaces before more text leaves its last space
+		// to the piece that follows, as in " word"
+		if end < len(text) && isSpaceRun(piece) {
+			if _, size := utf8.DecodeLastRuneInString(piece); size < len(piece) {
+				piece = piece[:len(piece)-size]
+				end -= size
+			}
+		}
+		pieces = append(pieces, piece)
+		pos = end
+	}
+	return pieces
+}
+
+// isSpaceRun reports whether a piece is white space without line breaks,
+// which only the last alternative of the patterns matches
+func isSpaceRun(piece string) bool {
+	for _, c := range piece {
+		if !unicode.IsSpace(c) || c == '\r' || c == '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// encode returns the ranks of the tokens of a piece, merging its bytes pair
+// by pair, the pair of lowest rank first and the leftmost of equal ranks.
+// Pairs wait in a heap and parts are linked by their neighbours, so long
+// pieces, such as a run of a single character, take O(n log n).
+func encode(piece string, ranks map[string]int) []int {
+	if rank, ok := ranks[piece]; ok {
+		return []int{rank}
+	}
+
+	// Parts are named by the offset they start at, and linked to the
+	// offsets of the parts around them, len(piece) ending the list
+	n := len(piece)
+	next := make([]int, n)
+	prev := make([]int, n)
+	for i := range next {
+		next[i] = i + 1
+		prev[i] = i - 1
+	}
+	pairs := &pairHeap{}
+	push := func(start int) {
+		if start < 0 || next[start] >= n {
+			return
+		}
+		end := next[next[start]]
+		if rank, ok := ranks[piece[start:end]]; ok {
+			heap.Push(pairs, pair{rank: rank, start: start, end: end})
+		}
+	}
+	for i := 0; i < n; i++ {
+		push(i)
+	}
+
+	merged := make([]bool, n)
+	for pairs.Len() > 0 {
+		p := heap.Pop(pairs).(pair)
+		// Pairs whose parts have changed since they were pushed are stale
+		if merged[p.start] || next[p.start] >= n || next[next[p.start]] != p.end {
+			continue
+		}
+		second := next[p.start]
+		merged[second] = true
+		next[p.start] = next[second]
+		if next[second] < n {
+			prev[next[second]] = p.start
+		}
+		push(p.start)
+		push(prev[p.start])
+	}
+
+	var tokens []int
+	for start := 0; start < n; start = next[start] {
+		tokens = append(tokens, ranks[piece[start:next[start]]])
+	}
+	return tokens
+}
+
+// pair is two neighbouring parts that merge into a token of rank
+type pair struct {
+	rank, start, end int
+}
+
+// pairHeap orders pairs by rank, then from left to right
+type pairHeap []pair
+
+func (h pairHeap) Len() int { return len(h) }
+func (h pairHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].start < h[j].start
+}
+func (h pairHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *pairHeap) Push(x interface{}) { *h = append(*h, x.(pair)) }
+func (h *pairHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,59 @@
+// Package tokens counts how many tokens a text takes in the tokenizers of
+// common LLMs and truncates text to a token budget.
+//
+// Counts are exact: the text is split by the tokenizer's own pre-tokenizer
+// and each piece encoded with the byte pair merges of the vocabulary, which
+// is embedded in the binary.
+package tokens
+
+import (
+	"fmt"
+)
+
+// Encoding names a tokenizer vocabulary
+type Encoding string
+
+const (
+	CL100K Encoding = "cl100k_base" // GPT-4 and GPT-3.5, close to most current models
+	O200K  Encoding = "o200k_base"  // GPT-4o and later, more compact on non-English text
+)
+
+// Encodings lists the supported encodings
+var Encodings = []Encoding{CL100K, O200K}
+
+// Valid reports whether e is a known encoding, the empty encoding meaning
+// CL100K
+func (e Encoding) Valid() bool {
+	if e == "" {
+		return true
+	}
+	for _, encoding := range Encodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate reports whether e is a known encoding
+func (e Encoding) Validate() error {
+	if !e.Valid() {
+		return fmt.Errorf("invalid tokenizer %q: must be one of %s", e, Encodings)
+	}
+	return nil
+}
+
+// Count returns the number of tokens of text in the encoding, unknown
+// encodings counting as CL100K
+func Count(text string, encoding Encoding) int {
+	if _, ok := vocabularies[encoding]; !ok {
+		encoding = CL100K
+	}
+	ranks := vocabularies[encoding].load(encoding)
+
+	count := 0
+	for _, piece := range split(text, encoding) {
+		count += len(encode(piece, ranks))
+	}
+	return count
+}
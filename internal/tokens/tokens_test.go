@@ -0,0 +1,106 @@
+package tokens
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		text     string
+		encoding Encoding
+		expected int
+	}{
+		{"", CL100K, 0},
+		{"hello world", CL100K, 2},
+		{"The quick brown fox jumps over the lazy dog.", CL100K, 10},
+		{"It's 2024, isn't it?", CL100K, 10},
+		{"It's 2024, isn't it?", O200K, 8},
+		{"internationalization", CL100K, 2},
+		{"\n\n", CL100K, 1},
+		{"a   b\n\n  c   ", CL100K, 7},
+		{"你好世界", CL100K, 5},
+		{"你好世界", O200K, 2},
+		{"hello world", "unknown", 2},
+	}
+
+	for _, tt := range tests {
+		if got := Count(tt.text, tt.encoding); got != tt.expected {
+			t.Errorf("Count(%q, %s) = %d, expected %d", tt.text, tt.encoding, got, tt.expected)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		text     string
+		encoding Encoding
+		expected []int
+	}{
+		{"hello world", CL100K, []int{15339, 1917}},
+		{"hello world", O200K, []int{24912, 2375}},
+		{"The quick brown fox jumps over the lazy dog.", CL100K, []int{791, 4062, 14198, 39935, 35308, 927, 279, 16053, 5679, 13}},
+	}
+
+	for _, tt := range tests {
+		ranks := vocabularies[tt.encoding].load(tt.encoding)
+		var got []int
+		for _, piece := range split(tt.text, tt.encoding) {
+			got = append(got, encode(piece, ranks)...)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("encoding %q in %s: expected %v, got %v", tt.text, tt.encoding, tt.expected, got)
+		}
+	}
+}
+
+func TestEncodingValidate(t *testing.T) {
+	for _, e := range []Encoding{"", CL100K, O200K} {
+		if err := e.Validate(); err != nil {
+			t.Errorf("expected %q to be valid: %v", e, err)
+		}
+	}
+	if err := Encoding("p50k_base").Validate(); err == nil {
+		t.Error("expected unknown encoding to be invalid")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	section := func(title string) string {
+		return "## " + title + "\n\n" + strings.Repeat("Some words in a paragraph. ", 20)
+	}
+	text := "# Guide\n\nIntro.\n\n" + section("One") + "\n\n" + section("Two") + "\n\n" + section("Three")
+
+	if out, truncated := Truncate(text, 10000, CL100K); truncated || out != text {
+		t.Error("expected text within the budget to be unchanged")
+	}
+
+	// Room for the first two sections and the marker
+	max := Count("# Guide\n\nIntro.\n\n"+section("One")+"\n\n"+section("Two"), CL100K) + 20
+	out, truncated := Truncate(text, max, CL100K)
+	if !truncated {
+		t.Fatal("expected text to be truncated")
+	}
+	if !strings.Contains(out, "## Two") || strings.Contains(out, "## Three") {
+		t.Errorf("expected truncation after the second section:\n%s", out)
+	}
+	if !strings.HasSuffix(out, " tokens shown]") {
+		t.Errorf("expected truncation marker:\n%s", out)
+	}
+	if count := Count(out, CL100K); count > max {
+		t.Errorf("expected at most %d tokens, got %d", max, count)
+	}
+
+	// A first section over the budget is cut at a paragraph
+	out, _ = Truncate("# Title\n\nFirst paragraph.\n\n"+strings.Repeat("word ", 500), 50, CL100K)
+	if !strings.HasPrefix(out, "# Title\n\nFirst paragraph.\n\n[Truncated:") {
+		t.Errorf("expected cut at a paragraph:\n%s", out)
+	}
+
+	// Code blocks are not split at their blank lines
+	blocks := splitBlocks("Text\n\n```go\na := 1\n\nb := 2\n```\n\nMore")
+	if len(blocks) != 3 || blocks[1] != "```go\na := 1\n\nb := 2\n```" {
+		t.Errorf("unexpected blocks: %q", blocks)
+	}
+}
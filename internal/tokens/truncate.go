@@ -0,0 +1,137 @@
+package tokens
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lines that open and close blocks which must not be split
+var fences = []string{"```", "~~~", "----", "....", "++++", "____"}
+
+// Truncate shortens text to at most max tokens, cutting at the end of a
+// section, or of a paragraph when the first section alone is too long, and
+// appending a marker that tells how much was left out. Text within the
+// budget is returned unchanged. It reports whether text was truncated.
+func Truncate(text string, max int, encoding Encoding) (string, bool) {
+	total := Count(text, encoding)
+	if max <= 0 || total <= max {
+		return text, false
+	}
+
+	budget := max - Count(marker(max, total), encoding)
+	sections := splitSections(splitBlocks(text))
+
+	var kept []string
+	used := 0
+	for _, section := range sections {
+		tokens := Count(strings.Join(section, "\n\n"), encoding)
+		if used+tokens > budget {
+			if len(kept) == 0 {
+				kept = fitBlocks(section, budget, encoding)
+			}
+			break
+		}
+		kept = append(kept, section...)
+		used += tokens
+	}
+
+	content := strings.Join(kept, "\n\n")
+	shown := Count(content, encoding)
+	out := marker(shown, total)
+	if content != "" {
+		out = strings.TrimRight(content, "\n") + "\n\n" + out
+	}
+	return out, true
+}
+
+func marker(shown, total int) string {
+	return fmt.Sprintf("[Truncated: %d of %d tokens shown]", shown, total)
+}
+
+// fitBlocks keeps the leading blocks of a section that fit the budget,
+// cutting a block that does not fit on its own at a line
+func fitBlocks(blocks []string, budget int, encoding Encoding) []string {
+	var kept []string
+	used := 0
+	for _, block := range blocks {
+		tokens := Count(block, encoding)
+		if used+tokens > budget {
+			if len(kept) == 0 {
+				var lines []string
+				for _, line := range strings.Split(block, "\n") {
+					if used += Count(line+"\n", encoding); used > budget {
+						break
+					}
+					lines = append(lines, line)
+				}
+				if len(lines) > 0 {
+					kept = append(kept, strings.Join(lines, "\n"))
+				}
+			}
+			break
+		}
+		kept = append(kept, block)
+		used += tokens
+	}
+	return kept
+}
+
+// splitBlocks splits text at blank lines, keeping fenced blocks whole
+func splitBlocks(text string) []string {
+	var blocks []string
+	var current []string
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case trimmed == "":
+			if len(current) > 0 {
+				blocks = append(blocks, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		default:
+			for _, f := range fences {
+				if strings.HasPrefix(trimmed, f) {
+					fence = f
+					break
+				}
+			}
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+// splitSections groups blocks into sections, each starting at a Markdown
+// or AsciiDoc heading
+func splitSections(blocks []string) [][]string {
+	var sections [][]string
+	for _, block := range blocks {
+		if isHeading(block) || len(sections) == 0 {
+			sections = append(sections, nil)
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], block)
+	}
+	return sections
+}
+
+func isHeading(block string) bool {
+	for _, mark := range []byte{'#', '='} {
+		i := 0
+		for i < len(block) && block[i] == mark {
+			i++
+		}
+		if i > 0 && i <= 6 && i < len(block) && block[i] == ' ' {
+			return true
+		}
+	}
+	return false
+}